@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+var (
+	errAudioMissing = errors.New("audio file is missing")
+	errAudioEmpty   = errors.New("audio file is empty (never recorded)")
+)
+
+// audioInfo is the stream information read from the header of an audio file
+type audioInfo struct {
+	format     string // wav, flac, or mp3
+	duration   time.Duration
+	sampleRate int
+	channels   int
+}
+
+func (ai audioInfo) String() string {
+	return fmt.Sprintf("%v %v %v Hz %v ch", ai.format,
+		formatPlaybackTime(ai.duration, false), ai.sampleRate, ai.channels)
+}
+
+// readAudioInfo reads the audio file header, determining the format from the
+// content rather than the file extension
+func readAudioInfo(path string) (ai audioInfo, err error) {
+	fi, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		return ai, errAudioMissing
+	case err != nil:
+		return ai, err
+	case fi.Size() == 0:
+		return ai, errAudioEmpty
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ai, err
+	}
+	defer f.Close()
+	magic := make([]byte, 12)
+	if _, err := io.ReadFull(f, magic); err != nil && err != io.ErrUnexpectedEOF {
+		return ai, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ai, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("RIFF")) && bytes.Equal(magic[8:12], []byte("WAVE")):
+		ai, err = readWAVInfo(f)
+	case bytes.HasPrefix(magic, []byte("fLaC")):
+		ai, err = readFLACInfo(f)
+	default:
+		// mp3 files may start with an id3 tag or directly with a frame
+		content, rerr := ioutil.ReadAll(f)
+		if rerr != nil {
+			return ai, rerr
+		}
+		ai, err = readMP3Info(content)
+	}
+	if err != nil {
+		return ai, fmt.Errorf("%v: %v", path, err)
+	}
+	return ai, nil
+}
+
+// readWAVInfo reads the fmt and data chunks of a riff wave file
+func readWAVInfo(r io.Reader) (ai audioInfo, err error) {
+	ai.format = "wav"
+	le := binary.LittleEndian
+	if _, err := io.CopyN(ioutil.Discard, r, 12); err != nil {
+		return ai, err
+	}
+	var byteRate uint32
+	fmtFound := false
+	for {
+		var chunk struct {
+			ID   [4]byte
+			Size uint32
+		}
+		if err := binary.Read(r, le, &chunk); err != nil {
+			return ai, errors.New("wav file has no data chunk")
+		}
+		switch string(chunk.ID[:]) {
+		case "fmt ":
+			var f struct {
+				Format, Channels     uint16
+				SampleRate, ByteRate uint32
+			}
+			if chunk.Size < 16 {
+				return ai, errors.New("bad wav fmt chunk")
+			}
+			if err := binary.Read(r, le, &f); err != nil {
+				return ai, err
+			}
+			ai.channels, ai.sampleRate, byteRate = int(f.Channels), int(f.SampleRate), f.ByteRate
+			chunk.Size -= 12
+			fmtFound = true
+		case "data":
+			if !fmtFound || byteRate == 0 {
+				return ai, errors.New("wav data chunk precedes the fmt chunk")
+			}
+			ai.duration = time.Duration(float64(chunk.Size) / float64(byteRate) * float64(time.Second))
+			return ai, nil
+		}
+		// chunks are padded to an even size
+		if _, err := io.CopyN(ioutil.Discard, r, int64(chunk.Size+chunk.Size%2)); err != nil {
+			return ai, errors.New("wav file has no data chunk")
+		}
+	}
+}
+
+// readFLACInfo reads the streaminfo metadata block, which is always first
+func readFLACInfo(r io.Reader) (ai audioInfo, err error) {
+	ai.format = "flac"
+	header := make([]byte, 4+4+34) // magic, block header, streaminfo
+	if _, err := io.ReadFull(r, header); err != nil {
+		return ai, errors.New("flac file is truncated")
+	}
+	if header[4]&0x7f != 0 {
+		return ai, errors.New("flac file does not start with streaminfo")
+	}
+	si := header[8:]
+
+	// 20 bits sample rate, 3 bits channels-1, 5 bits bits per sample-1,
+	// 36 bits total samples
+	bits := binary.BigEndian.Uint64(si[10:18])
+	ai.sampleRate = int(bits >> 44)
+	ai.channels = int(bits>>41&0x7) + 1
+	totalSamples := bits & (1<<36 - 1)
+	if ai.sampleRate == 0 {
+		return ai, errors.New("bad flac sample rate")
+	}
+	ai.duration = time.Duration(float64(totalSamples) / float64(ai.sampleRate) * float64(time.Second))
+	return ai, nil
+}
+
+var (
+	// bitrates (kbps) by [mpeg1][layer-1][index]
+	mp3Bitrates = [2][3][16]int{
+		{ // mpeg 2 and 2.5
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		},
+		{ // mpeg 1
+			{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+			{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		},
+	}
+	mp3SampleRates = [3]int{44100, 48000, 32000} // for mpeg 1
+)
+
+// mp3Frame is the information within the header of an mp3 frame
+type mp3Frame struct {
+	length     int // bytes
+	samples    int
+	sampleRate int
+	channels   int
+}
+
+func parseMP3FrameHeader(h []byte) (fr mp3Frame, ok bool) {
+	if len(h) < 4 || h[0] != 0xff || h[1]&0xe0 != 0xe0 {
+		return fr, false
+	}
+	version := h[1] >> 3 & 0x3 // 0: 2.5, 2: 2, 3: 1
+	layer := 4 - int(h[1]>>1&0x3)
+	bitrateI := h[2] >> 4
+	sampleRateI := h[2] >> 2 & 0x3
+	if version == 1 || layer == 4 || bitrateI == 0 || bitrateI == 0xf || sampleRateI == 3 {
+		return fr, false // reserved or free format
+	}
+	mpeg1 := 0
+	if version == 3 {
+		mpeg1 = 1
+	}
+	bitrate := mp3Bitrates[mpeg1][layer-1][bitrateI] * 1000
+	fr.sampleRate = mp3SampleRates[sampleRateI]
+	switch version {
+	case 2:
+		fr.sampleRate /= 2
+	case 0:
+		fr.sampleRate /= 4
+	}
+	padding := int(h[2] >> 1 & 0x1)
+	fr.channels = 2
+	if h[3]>>6 == 3 {
+		fr.channels = 1
+	}
+
+	switch {
+	case layer == 1:
+		fr.samples = 384
+		fr.length = (12*bitrate/fr.sampleRate + padding) * 4
+	case layer == 3 && mpeg1 == 0:
+		fr.samples = 576
+		fr.length = 72*bitrate/fr.sampleRate + padding
+	default:
+		fr.samples = 1152
+		fr.length = 144*bitrate/fr.sampleRate + padding
+	}
+	return fr, true
+}
+
+// readMP3Info determines the duration of the mp3 by walking its frames,
+// which is exact for both constant and variable bitrates
+func readMP3Info(content []byte) (ai audioInfo, err error) {
+	ai.format = "mp3"
+
+	// skip any id3v2 tag
+	pos := 0
+	if len(content) >= 10 && bytes.HasPrefix(content, []byte("ID3")) {
+		size := int(content[6])<<21 | int(content[7])<<14 | int(content[8])<<7 | int(content[9])
+		pos = 10 + size
+		if content[5]&0x10 != 0 {
+			pos += 10 // footer
+		}
+	}
+
+	// find the first frame which is followed by another frame
+	// (so stray sync bits within the tag aren't mistaken for a frame)
+	for ; pos+4 <= len(content); pos++ {
+		fr, ok := parseMP3FrameHeader(content[pos:])
+		if !ok {
+			continue
+		}
+		next := pos + fr.length
+		if next+4 > len(content) {
+			break
+		}
+		if _, ok := parseMP3FrameHeader(content[next:]); ok {
+			break
+		}
+	}
+
+	samples, frames := 0, 0
+	for pos+4 <= len(content) {
+		fr, ok := parseMP3FrameHeader(content[pos:])
+		if !ok {
+			break // an id3v1 tag or trailing garbage
+		}
+		if frames == 0 {
+			ai.sampleRate, ai.channels = fr.sampleRate, fr.channels
+		}
+		samples += fr.samples
+		frames++
+		pos += fr.length
+	}
+	if frames == 0 {
+		return ai, errors.New("unrecognized audio format (expected wav, flac, or mp3)")
+	}
+	ai.duration = time.Duration(float64(samples) / float64(ai.sampleRate) * float64(time.Second))
+	return ai, nil
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"math"
+	"math/cmplx"
+	"sort"
+	"time"
+)
+
+// onset and beat detection after Ellis, "Beat Tracking by Dynamic
+// Programming" (2007): a spectral flux onset envelope, a tempo estimate from
+// its autocorrelation, and the beats which best fit both
+
+const (
+	beatSampleRate = 11025 // the audio is downsampled for the analysis
+	beatFrameLen   = 512   // samples, must be a power of 2
+	beatHopLen     = 128   // samples
+
+	beatTightness = 100.0 // how strictly the beats keep to the tempo
+)
+
+var (
+	// beatHop is the duration of each frame of the onset envelope
+	beatHop = time.Duration(beatHopLen) * time.Second / beatSampleRate
+
+	// the onset of a frame is heard around the middle of its window
+	beatFrameCenter = time.Duration(beatFrameLen/2) * time.Second / beatSampleRate
+)
+
+// beatTrack is the result of the beat detection
+type beatTrack struct {
+	onsets []float64 // onset strength of every frame
+	period float64   // frames per beat
+	beats  []int     // frame of every beat
+}
+
+// downsample averages the samples down to roughly the beat sample rate
+func downsample(samples []float64, sampleRate int) []float64 {
+	factor := int(math.Round(float64(sampleRate) / beatSampleRate))
+	if factor <= 1 {
+		return samples
+	}
+	out := make([]float64, len(samples)/factor)
+	for i := range out {
+		sum := 0.0
+		for _, s := range samples[i*factor : (i+1)*factor] {
+			sum += s
+		}
+		out[i] = sum / float64(factor)
+	}
+	return out
+}
+
+// fft is an in place radix-2 fast fourier transform, len(x) must be a power of 2
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Rect(1, -2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], x[start+k+size/2]*w
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				w *= step
+			}
+		}
+	}
+}
+
+// onsetEnvelope returns the spectral flux of the (downsampled) samples,
+// the increase in log magnitude over all frequencies from frame to frame,
+// with the local average removed
+func onsetEnvelope(samples []float64) []float64 {
+	if len(samples) < beatFrameLen {
+		return nil
+	}
+	window := make([]float64, beatFrameLen) // hann
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/beatFrameLen)
+	}
+
+	frames := (len(samples)-beatFrameLen)/beatHopLen + 1
+	flux := make([]float64, frames)
+	prev := make([]float64, beatFrameLen/2)
+	buf := make([]complex128, beatFrameLen)
+	for f := 0; f < frames; f++ {
+		for i := range buf {
+			buf[i] = complex(samples[f*beatHopLen+i]*window[i], 0)
+		}
+		fft(buf)
+		for k := range prev {
+			mag := math.Log1p(100 * cmplx.Abs(buf[k]))
+			if f > 0 && mag > prev[k] {
+				flux[f] += mag - prev[k]
+			}
+			prev[k] = mag
+		}
+	}
+
+	// remove the local average (over about half a second)
+	half := int(0.25 / beatHop.Seconds())
+	onsets := make([]float64, frames)
+	for f := range flux {
+		lo, hi := f-half, f+half+1
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > frames {
+			hi = frames
+		}
+		sum := 0.0
+		for _, v := range flux[lo:hi] {
+			sum += v
+		}
+		onsets[f] = math.Max(0, flux[f]-sum/float64(hi-lo))
+	}
+
+	// normalize by the standard deviation
+	sd := 0.0
+	for _, v := range onsets {
+		sd += v * v
+	}
+	sd = math.Sqrt(sd / float64(frames))
+	if sd > 0 {
+		for f := range onsets {
+			onsets[f] /= sd
+		}
+	}
+	return onsets
+}
+
+// estimatePeriod returns the beat period (in frames) with the strongest
+// autocorrelation of the onsets within the bpm range, weighted towards the
+// preferred bpm
+func estimatePeriod(onsets []float64, minBPM, maxBPM, preferredBPM float64) float64 {
+	framesPerMinute := time.Minute.Seconds() / beatHop.Seconds()
+	minLag := int(framesPerMinute / maxBPM)
+	maxLag := int(framesPerMinute/minBPM) + 1
+	if minLag < 1 {
+		minLag = 1
+	}
+	preferred := framesPerMinute / preferredBPM
+
+	bestLag, best := minLag, math.Inf(-1)
+	for lag := minLag; lag <= maxLag && lag < len(onsets); lag++ {
+		ac := 0.0
+		for i := lag; i < len(onsets); i++ {
+			ac += onsets[i] * onsets[i-lag]
+		}
+		ac /= float64(len(onsets) - lag)
+		octaves := math.Log2(float64(lag) / preferred)
+		weighted := ac * math.Exp(-0.5*octaves*octaves) // log-gaussian, one octave wide
+		if weighted > best {
+			bestLag, best = lag, weighted
+		}
+	}
+	return float64(bestLag)
+}
+
+// trackBeats finds the beats which maximize the onset strength while
+// keeping close to the period, leading and trailing beats which are weaker
+// than the rest (the silence before and after the song) are dropped
+func trackBeats(onsets []float64, period float64) (beats []int) {
+	n := len(onsets)
+	if n == 0 {
+		return nil
+	}
+	score := make([]float64, n)
+	backlink := make([]int, n)
+	for t := range onsets {
+		backlink[t] = -1
+		best := 0.0
+		lo, hi := t-int(math.Round(2*period)), t-int(math.Round(period/2))
+		if lo < 0 {
+			lo = 0
+		}
+		for prev := lo; prev <= hi; prev++ {
+			r := math.Log(float64(t-prev) / period)
+			s := score[prev] - beatTightness*r*r
+			if backlink[t] < 0 || s > best {
+				best, backlink[t] = s, prev
+			}
+		}
+		score[t] = onsets[t] + math.Max(0, best)
+		if best <= 0 {
+			backlink[t] = -1 // better to start afresh
+		}
+	}
+
+	// the final beat is the best scoring within the final period
+	last := n - 1
+	for t := n - 1; t >= 0 && t > n-1-int(period); t-- {
+		if score[t] > score[last] {
+			last = t
+		}
+	}
+	for t := last; t >= 0; t = backlink[t] {
+		beats = append(beats, t)
+	}
+	for i, j := 0, len(beats)-1; i < j; i, j = i+1, j-1 {
+		beats[i], beats[j] = beats[j], beats[i]
+	}
+
+	// trim the weak beats at either end
+	rms := 0.0
+	for _, b := range beats {
+		rms += onsets[b] * onsets[b]
+	}
+	threshold := 0.5 * math.Sqrt(rms/float64(len(beats)))
+	for len(beats) > 0 && beatStrength(onsets, beats[0]) < threshold {
+		beats = beats[1:]
+	}
+	for len(beats) > 0 && beatStrength(onsets, beats[len(beats)-1]) < threshold {
+		beats = beats[:len(beats)-1]
+	}
+	return beats
+}
+
+// beatStrength is the strongest onset within a couple frames of the beat
+func beatStrength(onsets []float64, frame int) (max float64) {
+	for f := frame - 2; f <= frame+2; f++ {
+		if f >= 0 && f < len(onsets) {
+			max = math.Max(max, onsets[f])
+		}
+	}
+	return max
+}
+
+// detectBeats runs the beat detection over the samples
+func detectBeats(samples []float64, sampleRate int, minBPM, maxBPM, preferredBPM float64) beatTrack {
+	bt := beatTrack{onsets: onsetEnvelope(downsample(samples, sampleRate))}
+	bt.period = estimatePeriod(bt.onsets, minBPM, maxBPM, preferredBPM)
+	bt.beats = trackBeats(bt.onsets, bt.period)
+	return bt
+}
+
+// bpm returns the tempo of the detected beats
+func (bt beatTrack) bpm() float64 {
+	return time.Minute.Seconds() / (bt.period * beatHop.Seconds())
+}
+
+// at returns the playback duration of the (fractional) beat index,
+// extrapolated at the detected tempo beyond the first or last beat
+func (bt beatTrack) at(beat float64) time.Duration {
+	frame := func(i int) float64 { return float64(bt.beats[i]) }
+	var f float64
+	switch i := int(math.Floor(beat)); {
+	case beat < 0:
+		f = frame(0) + beat*bt.period
+	case i >= len(bt.beats)-1:
+		f = frame(len(bt.beats)-1) + (beat-float64(len(bt.beats)-1))*bt.period
+	default:
+		f = frame(i) + (beat-float64(i))*(frame(i+1)-frame(i))
+	}
+	return time.Duration(f*float64(beatHop)) + beatFrameCenter
+}
+
+// nearest returns the index of the beat nearest to the playback duration
+func (bt beatTrack) nearest(d time.Duration) int {
+	frame := float64(d-beatFrameCenter) / float64(beatHop)
+	i := sort.SearchInts(bt.beats, int(frame))
+	if i > 0 && (i == len(bt.beats) || frame-float64(bt.beats[i-1]) < float64(bt.beats[i])-frame) {
+		i--
+	}
+	return i
+}
+
+// confidence scores (0 to 1) how clearly the beat was detected, from its
+// onset strength relative to the other beats and how evenly it follows the
+// previous beat, beats which were extrapolated have no confidence
+func (bt beatTrack) confidence(beat int) float64 {
+	if beat < 0 || beat >= len(bt.beats) {
+		return 0
+	}
+	strengths := []float64{}
+	for _, b := range bt.beats {
+		strengths = append(strengths, beatStrength(bt.onsets, b))
+	}
+	sort.Float64s(strengths)
+	reference := strengths[len(strengths)*9/10] // 90th percentile
+	strength := 1.0
+	if reference > 0 {
+		strength = math.Min(1, beatStrength(bt.onsets, bt.beats[beat])/reference)
+	}
+
+	evenness := 1.0
+	if beat > 0 {
+		interval := float64(bt.beats[beat] - bt.beats[beat-1])
+		evenness = math.Max(0, 1-math.Abs(interval-bt.period)/bt.period)
+	}
+	return strength * evenness
+}
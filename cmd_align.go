@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	AlignCmd = &cobra.Command{
+		Use:   "align [filepath]",
+		Short: "suggest the playback times of the sines from the beats of the linked audio",
+		Long: `detect the beats of the linked (wav) audio and match them to the humps of
+the songsheet, one beat per hump, writing a suggested playback time line
+(mm:ss.cs) under each sine along with the confidence (0 to 1) of the
+detected beat, for instance:
+
+  00:12.34 // confidence 0.87
+
+The first hump is matched to the beat nearest to --start, otherwise to the
+beat nearest any existing playback time, otherwise to the first beat of the
+audio. The tempo search is centred on --bpm (default the header bpm). Sines
+which already have playback times are kept unless --overwrite.`,
+		Args: cobra.ExactArgs(1),
+		RunE: alignCmd,
+	}
+
+	alignStartFlag     string
+	alignBPMFlag       float64
+	alignOverwriteFlag bool
+	alignDryRunFlag    bool
+)
+
+func init() {
+	AlignCmd.PersistentFlags().StringVar(
+		&alignStartFlag, "start", "",
+		"approximate playback time of the first hump (mm:ss.cs)")
+	AlignCmd.PersistentFlags().Float64Var(
+		&alignBPMFlag, "bpm", 0,
+		"approximate bpm of the humps (default the header bpm)")
+	AlignCmd.PersistentFlags().BoolVar(
+		&alignOverwriteFlag, "overwrite", false,
+		"replace the existing playback times")
+	AlignCmd.PersistentFlags().BoolVar(
+		&alignDryRunFlag, "dry-run", false,
+		"only print the suggested playback times")
+	registerTakeFlag(AlignCmd)
+	RootCmd.AddCommand(AlignCmd)
+}
+
+const alignConfidencePrefix = " " + commentPrefix + " confidence "
+
+func alignCmd(cmd *cobra.Command, args []string) error {
+	filepath := args[0]
+	content, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return err
+	}
+	origLines := strings.Split(string(content), "\n")
+	take, err := songTake(origLines)
+	if err != nil {
+		return err
+	}
+	lines, origIndexes := deleteCommentsMapped(origLines)
+	_, hc, err := parseHeader(lines)
+	if err != nil {
+		return err
+	}
+	lasses := getLasses(lines, take)
+	if len(lasses) == 0 {
+		return errors.New("no sines found within the songsheet")
+	}
+	charPoss := lasses.charPositions()
+
+	// decode the audio
+	has, audioPath, err := hasSongsheetAudio(origLines, take)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errors.New("the songsheet has no linked audio to align to")
+	}
+	if _, err := readAudioInfo(audioPath); err != nil {
+		return err
+	}
+	audio, err := ioutil.ReadFile(audioPath)
+	if err != nil {
+		return err
+	}
+	samples, sampleRate, err := decodeWAV(audio)
+	if err != nil {
+		return fmt.Errorf("align requires wav audio: %v", err)
+	}
+
+	// search near the known bpm, or else across all the usual tempos
+	minBPM, maxBPM, bpm := 50.0, 200.0, 120.0
+	headerBPMVal, err := strconv.ParseFloat(strings.TrimSpace(hc.bpm), 64)
+	switch {
+	case alignBPMFlag < 0:
+		return fmt.Errorf("bad bpm: %v", alignBPMFlag)
+	case alignBPMFlag > 0:
+		bpm = alignBPMFlag
+		minBPM, maxBPM = bpm/1.2, bpm*1.2
+	case err == nil && headerBPMVal > 0:
+		bpm = headerBPMVal
+		minBPM, maxBPM = bpm/1.2, bpm*1.2
+	}
+	bt := detectBeats(samples, sampleRate, minBPM, maxBPM, bpm)
+	if len(bt.beats) < 2 {
+		return errors.New("no beats could be detected within the audio")
+	}
+	fmt.Printf("detected %v beats at %v bpm\n", len(bt.beats), int(math.Round(bt.bpm())))
+
+	// the beat of the first hump
+	firstBeat := 0.0
+	switch {
+	case alignStartFlag != "":
+		start, _, found := getPlaybackTimeFromLine(alignStartFlag)
+		if !found {
+			return fmt.Errorf("could not parse playback time: %v", alignStartFlag)
+		}
+		firstBeat = float64(bt.nearest(start.elapsed()))
+	default:
+		for i, cp := range charPoss {
+			if cp.hasPT {
+				firstBeat = float64(bt.nearest(cp.pt.elapsed())) - float64(i)/charsToaHump
+				break
+			}
+		}
+	}
+
+	// suggest the playback time of the start of each sine by original line index
+	inserts := make(map[int]string)  // lines to insert after
+	replaces := make(map[int]string) // lines to replace
+	charI := 0
+	for i, ls := range lasses {
+		firstCharI := charI
+		for charI < len(charPoss) && charPoss[charI].lassesIndex == i {
+			charI++
+		}
+		lineNo := originalLineNo(origIndexes, int(ls.lineNo)+1)
+		if ls.sas.hasPlaybackTime() && !alignOverwriteFlag {
+			fmt.Printf("line %v: kept %v\n", lineNo, ls.sas.playbackTimes[0].pt.str)
+			continue
+		}
+
+		beat := firstBeat + float64(firstCharI)/charsToaHump
+		d := bt.at(beat)
+		if d < 0 {
+			fmt.Printf("line %v: before the start of the audio\n", lineNo)
+			continue
+		}
+		pt := playbackTime{}.AddDur(d.Round(10 * time.Millisecond))
+		confidence := bt.confidence(int(math.Round(beat)))
+		fmt.Printf("line %v: %v confidence %.2f\n", lineNo, pt.str, confidence)
+
+		ptLine := formatTakePlaybackTimesLine(take, []sinePlaybackTime{{pt, 0}}) +
+			fmt.Sprintf("%v%.2f", alignConfidencePrefix, confidence)
+		if ls.sas.hasPlaybackTime() {
+			replaces[origIndexes[int(ls.lineNo)+ls.sas.ptLineOffset]] = ptLine
+			continue
+		}
+		inserts[origIndexes[int(ls.lineNo)+3+ls.sas.ptLines]] = ptLine
+	}
+	if alignDryRunFlag {
+		return nil
+	}
+
+	out := []string{}
+	for i, line := range origLines {
+		if ptLine, found := replaces[i]; found {
+			line = ptLine
+		}
+		out = append(out, line)
+		if ptLine, found := inserts[i]; found {
+			out = append(out, ptLine)
+		}
+	}
+	return writeSongsheet(filepath, out)
+}
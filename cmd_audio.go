@@ -3,7 +3,7 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
-	"strconv"
+	"os"
 	"strings"
 
 	"github.com/rigelrozanski/thranch/quac"
@@ -21,36 +21,40 @@ var (
 	HasAudioCmd = &cobra.Command{
 		Use:   "has-audio [filepath]",
 		Short: "print TRUE or FALSE if the file has associated audio",
-		Args:  cobra.ExactArgs(1),
-		RunE:  hasAudioCmd,
+		Long: `print TRUE or FALSE if the file has associated audio. The audio file
+header (wav, flac, or mp3) is read to verify the recording, a missing or
+empty audio file (allocated but never recorded) is reported as FALSE. Audio
+of other formats (or whose header can't be read) is reported as TRUE along
+with a warning. A warning is also given if the last playback time of the
+song is past the end of the audio.`,
+		Args: cobra.ExactArgs(1),
+		RunE: hasAudioCmd,
 	}
+
+	hasAudioInfoFlag bool
 )
 
 func init() {
+	HasAudioCmd.PersistentFlags().BoolVar(
+		&hasAudioInfoFlag, "info", false,
+		"also print the format, duration, sample rate, and channels of the audio")
+	registerTakeFlag(GetAudioCmd)
+	registerTakeFlag(HasAudioCmd)
 	RootCmd.AddCommand(GetAudioCmd)
 	RootCmd.AddCommand(HasAudioCmd)
 }
 
-const (
-	audioLinePrefix = commentPrefix + " AUDIO-ID="
-)
-
-func hasSongsheetAudio(lines []string) (yesitdoes bool, audiofilepath string, err error) {
-	for _, line := range lines {
-		if strings.HasPrefix(line, audioLinePrefix) {
-			quidStr := strings.TrimPrefix(line, audioLinePrefix)
-			quid, err := strconv.Atoi(quidStr)
-			if err != nil {
-				return false, "", err
-			}
-			audiofilepath, found := quac.GetFilepathByID(uint32(quid))
-			if !found {
-				return false, "", nil
-			}
-			return true, audiofilepath, nil
-		}
-	}
-	return false, "", nil
+// hasSongsheetAudio returns the audio filepath of the take with the label
+func hasSongsheetAudio(lines []string, label string) (yesitdoes bool, audiofilepath string, err error) {
+	take, found, err := getTake(lines, label)
+	if err != nil || !found {
+		return false, "", err
+	}
+	audiofilepath, found = quac.GetFilepathByID(take.quid)
+	if !found {
+		return false, "", nil
+	}
+	return true, audiofilepath, nil
 }
 
 func hasAudioCmd(cmd *cobra.Command, args []string) error {
@@ -59,15 +63,43 @@ func hasAudioCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	lines := strings.Split(string(content), "\n")
-	has, _, err := hasSongsheetAudio(lines)
+	take, err := songTake(lines)
 	if err != nil {
 		fmt.Printf("FALSE")
 		return err
 	}
-	if has {
-		fmt.Printf("TRUE")
-	} else {
+	has, audioPath, err := hasSongsheetAudio(lines, take)
+	if err != nil {
+		fmt.Printf("FALSE")
+		return err
+	}
+	if !has {
 		fmt.Printf("FALSE")
+		return nil
+	}
+
+	ai, err := readAudioInfo(audioPath)
+	switch {
+	case err == errAudioMissing || err == errAudioEmpty:
+		fmt.Printf("FALSE")
+		fmt.Fprintf(os.Stderr, "%v: %v\n", err, audioPath)
+		return nil
+	case err != nil:
+		// the recording exists, it just can't be verified
+		fmt.Printf("TRUE")
+		fmt.Fprintf(os.Stderr, "warning: could not read the audio header: %v\n", err)
+		return nil
+	}
+	fmt.Printf("TRUE")
+	if hasAudioInfoFlag {
+		fmt.Printf("\n%v", ai)
+	}
+
+	// the playback times should all be within the recording
+	lasses := getLasses(deleteComments(lines), take)
+	if pt, _, found := lasses.lastPlaybackTime(); found && pt.elapsed() > ai.duration {
+		fmt.Fprintf(os.Stderr, "warning: the last playback time %v is past the end of the audio (%v)\n",
+			pt.str, formatPlaybackTime(ai.duration, pt.millis))
 	}
 	return nil
 }
@@ -78,8 +110,12 @@ func audioFPCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	lines := strings.Split(string(content), "\n")
+	take, err := songTake(lines)
+	if err != nil {
+		return err
+	}
 
-	has, filepath, err := hasSongsheetAudio(lines)
+	has, filepath, err := hasSongsheetAudio(lines, take)
 	if err != nil {
 		return err
 	}
@@ -94,9 +130,8 @@ func audioFPCmd(cmd *cobra.Command, args []string) error {
 	clumpedTags := origIdea.GetClumpedTags()
 	// add the original tags to this new entry
 	audioFilepath, quID := quac.NewEmptyAudioEntry(clumpedTags)
-	newLine := fmt.Sprintf("%v%v", audioLinePrefix, quID)
-	content = []byte(newLine + "\n" + string(content))
-	err = ioutil.WriteFile(args[0], content, 0666)
+	lines = setDirective(lines, takeDirectiveKey(take), fmt.Sprintf("%v", quID))
+	err = writeSongsheet(args[0], lines)
 	if err != nil {
 		return err
 	}
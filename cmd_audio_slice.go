@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	AudioSliceCmd = &cobra.Command{
+		Use:   "audio-slice [filepath]",
+		Short: "cut the linked audio into a clip for each section of the songsheet",
+		Long: `cut the linked (wav) audio into a clip for each section of the songsheet,
+timed by the playback times as per the pt command. A section starts at the
+first sine following a section comment:
+
+  // SECTION=bridge
+
+and runs until the next section (the final section runs until the end of
+the audio). Alternatively the clips are cut at the sines of the --at line
+numbers. Each clip is faded in and out and named after the songsheet and
+the section, for instance songsheet_x_03_bridge.wav`,
+		Args: cobra.ExactArgs(1),
+		RunE: audioSliceCmd,
+	}
+
+	audioSliceAtFlag   []int
+	audioSliceFadeFlag time.Duration
+	audioSliceDirFlag  string
+)
+
+func init() {
+	AudioSliceCmd.PersistentFlags().IntSliceVar(
+		&audioSliceAtFlag, "at", []int{},
+		"line numbers of the sines to cut at (rather than the sections)")
+	AudioSliceCmd.PersistentFlags().DurationVar(
+		&audioSliceFadeFlag, "fade", 10*time.Millisecond,
+		"duration of the fade in and out of each clip")
+	AudioSliceCmd.PersistentFlags().StringVar(
+		&audioSliceDirFlag, "dir", ".",
+		"directory to write the clips to")
+	registerTakeFlag(AudioSliceCmd)
+	RootCmd.AddCommand(AudioSliceCmd)
+}
+
+// audioClip is a named portion of the song
+type audioClip struct {
+	label      string
+	start, end time.Duration
+	toEnd      bool // the clip runs until the end of the audio (rather than end)
+}
+
+func audioSliceCmd(cmd *cobra.Command, args []string) error {
+	ssPath := args[0]
+	content, err := ioutil.ReadFile(ssPath)
+	if err != nil {
+		return err
+	}
+	origLines := strings.Split(string(content), "\n")
+	take, err := songTake(origLines)
+	if err != nil {
+		return err
+	}
+	if audioSliceFadeFlag < 0 {
+		return fmt.Errorf("bad fade: %v", audioSliceFadeFlag)
+	}
+
+	clips, err := songClips(origLines, take, audioSliceAtFlag)
+	if err != nil {
+		return err
+	}
+	if len(clips) == 0 {
+		return fmt.Errorf("no sections found, add %v comments above "+
+			"the sines or use --at", formatDirective(sectionDirectiveKey, "name"))
+	}
+
+	// decode the audio
+	has, audioPath, err := hasSongsheetAudio(origLines, take)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errors.New("the songsheet has no linked audio to slice")
+	}
+	if _, err := readAudioInfo(audioPath); err != nil {
+		return err
+	}
+	audio, err := ioutil.ReadFile(audioPath)
+	if err != nil {
+		return err
+	}
+	chans, sampleRate, err := decodeWAVChannels(audio)
+	if err != nil {
+		return fmt.Errorf("audio-slice requires wav audio: %v", err)
+	}
+	audioLen := len(chans[0])
+	toSample := func(d time.Duration) int {
+		i := int(d.Seconds() * float64(sampleRate))
+		switch {
+		case i < 0:
+			return 0
+		case i > audioLen:
+			return audioLen
+		}
+		return i
+	}
+
+	base := filepath.Base(ssPath)
+	for n, clip := range clips {
+		start, end := toSample(clip.start), audioLen
+		if !clip.toEnd {
+			end = toSample(clip.end)
+		}
+		if end <= start {
+			fmt.Printf("skipping %v, it is beyond the end of the audio\n", clip.label)
+			continue
+		}
+
+		clipChans := make([][]float64, len(chans))
+		for c, samples := range chans {
+			clipChans[c] = append([]float64{}, samples[start:end]...)
+			fade(clipChans[c], int(audioSliceFadeFlag.Seconds()*float64(sampleRate)))
+		}
+		name := fmt.Sprintf("%v_%02d_%v.wav", base, n+1, fileLabel(clip.label))
+		path := filepath.Join(audioSliceDirFlag, name)
+		if err := writeFileAtomic(path, writeWAVChannels(clipChans, sampleRate)); err != nil {
+			return err
+		}
+		fmt.Printf("%v  %v - %v\n", path,
+			formatPlaybackTime(time.Duration(start)*time.Second/time.Duration(sampleRate), false),
+			formatPlaybackTime(time.Duration(end)*time.Second/time.Duration(sampleRate), false))
+	}
+	return nil
+}
+
+// songClips determines the clips of the song either at the sines of the
+// line numbers (starting from 1) or else at the section comments (if any),
+// timed by the playback times of the take
+func songClips(origLines []string, take string, atLineNos []int) (clips []audioClip, err error) {
+	lines, origIndexes := deleteCommentsMapped(origLines)
+	lasses := getLasses(lines, take)
+	if len(lasses) == 0 {
+		return nil, errors.New("no sines found within the songsheet")
+	}
+	charPoss := lasses.charPositions()
+	tl, anchored := newCharTimeline(lasses.charWeights(), charPoss, linesBPM(lines))
+	if !anchored {
+		return nil, errors.New("no playback times to time the clips with")
+	}
+
+	// the start time of each sine
+	sineStarts := make([]time.Duration, len(lasses))
+	for i := len(charPoss) - 1; i >= 0; i-- {
+		sineStarts[charPoss[i].lassesIndex] = tl[i]
+	}
+
+	// the index of the sine and label starting each clip
+	starts, labels := []int{}, []string{}
+	if len(atLineNos) > 0 {
+		for n, lineNo := range atLineNos {
+			curY := strippedLineNo(origIndexes, lineNo)
+			lasI := charPoss[lasses.cursorCharIndex(charPoss, 1, curY)].lassesIndex
+			starts = append(starts, lasI)
+			labels = append(labels, "part-"+strconv.Itoa(n+1))
+		}
+	} else {
+		for i, line := range origLines {
+			key, label, ok := parseDirective(line)
+			if !ok || key != sectionDirectiveKey {
+				continue
+			}
+			// the first sine following the section comment
+			for lasI, ls := range lasses {
+				if origIndexes[ls.lineNo] > i {
+					starts = append(starts, lasI)
+					labels = append(labels, label)
+					break
+				}
+			}
+		}
+	}
+
+	for n, lasI := range starts {
+		clip := audioClip{label: labels[n], start: sineStarts[lasI], toEnd: n+1 == len(starts)}
+		if !clip.toEnd {
+			clip.end = sineStarts[starts[n+1]]
+			if clip.end <= clip.start {
+				return nil, fmt.Errorf("the %v clip (%v) doesn't start after the %v clip (%v)",
+					ordinal(n+2), labels[n+1], ordinal(n+1), labels[n])
+			}
+		}
+		clips = append(clips, clip)
+	}
+	return clips, nil
+}
+
+// fade linearly fades the samples in and out over the number of samples
+func fade(samples []float64, fadeLen int) {
+	if fadeLen > len(samples)/2 {
+		fadeLen = len(samples) / 2
+	}
+	for i := 0; i < fadeLen; i++ {
+		gain := float64(i) / float64(fadeLen)
+		samples[i] *= gain
+		samples[len(samples)-1-i] *= gain
+	}
+}
+
+// fileLabel makes the label safe for a filename
+func fileLabel(label string) string {
+	if out := fileSafe(label); out != "" {
+		return out
+	}
+	return "section"
+}
+
+// fileSafe lowercases the string replacing all but letters and numbers with
+// dashes, empty if there are no (ascii) letters or numbers
+func fileSafe(s string) string {
+	out := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '-'
+	}, s)
+	return strings.Trim(out, "-")
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/rigelrozanski/thranch/quac"
+	"github.com/spf13/cobra"
+)
+
+var (
+	BookCmd = &cobra.Command{
+		Use:   "book [songsheets...]",
+		Short: "compile many songsheets into a single songbook pdf",
+		Long: `compile many songsheets into a single songbook pdf with a title page,
+table of contents, page numbers, and bookmarks for each song.
+
+songsheets may be provided as any mix of:
+    filepaths:    ./songsheet_my-song
+    qu-ids:       1234
+    tag queries:  tag:folk,originals  (all songsheets with every tag)`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: bookCmd,
+	}
+
+	bookTitleFlag string
+)
+
+func init() {
+	BookCmd.PersistentFlags().StringVar(
+		&bookTitleFlag, "title", "Songbook",
+		"title of the songbook")
+	BookCmd.PersistentFlags().BoolVar(
+		&mirrorStringsOrderFlag, "mirror", false,
+		"mirror string positions")
+	BookCmd.PersistentFlags().Uint16Var(
+		&numColumnsFlag, "columns", 2,
+		"number of columns to print each song into")
+	BookCmd.PersistentFlags().Float64Var(
+		&spacingRatioFlag, "spacing-ratio", 1.5,
+		"ratio of the spacing to the lyric-lines")
+	BookCmd.PersistentFlags().Float64Var(
+		&sineAmplitudeRatioFlag, "amp-ratio", 0.8,
+		"ratio of amplitude of the sine curve to the lyric text")
+	BookCmd.PersistentFlags().StringVar(
+		&fontFlag, "font", "",
+		"filepath to a monospaced UTF-8 (.ttf) font for non-latin text")
+	BookCmd.PersistentFlags().StringVar(
+		&fontBoldFlag, "font-bold", "",
+		"filepath to the bold variant of the UTF-8 font")
+	registerPageFlags(BookCmd)
+	RootCmd.AddCommand(BookCmd)
+}
+
+const tagQueryPrefix = "tag:"
+
+// songsheetSource is the raw content of a songsheet along with
+// where it was loaded from
+type songsheetSource struct {
+	name    string // filepath or qu-id
+	content []byte
+}
+
+// resolveSongsheets loads the content of all the songsheets referred to by the
+// args, in the order provided, tag queries are sorted by qu-id
+func resolveSongsheets(args []string) (sources []songsheetSource, err error) {
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, tagQueryPrefix):
+			tags := strings.Split(strings.TrimPrefix(arg, tagQueryPrefix), ",")
+			found, err := songsheetsWithTags(tags)
+			if err != nil {
+				return nil, err
+			}
+			if len(found) == 0 {
+				return nil, fmt.Errorf("no songsheets found for %v", arg)
+			}
+			sources = append(sources, found...)
+
+		case fileExists(arg):
+			content, err := ioutil.ReadFile(arg)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, songsheetSource{arg, content})
+
+		default:
+			quid, err := strconv.Atoi(arg)
+			if err != nil {
+				return nil, fmt.Errorf("%v is neither a file, a qu-id, nor a tag query", arg)
+			}
+			content, found := quac.GetContentByID(uint32(quid))
+			if !found {
+				return nil, fmt.Errorf("could not find anything under id: %v", quid)
+			}
+			sources = append(sources, songsheetSource{arg, content})
+		}
+	}
+	return sources, nil
+}
+
+// songsheetsWithTags returns all the songsheets which have every provided tag
+func songsheetsWithTags(tags []string) (sources []songsheetSource, err error) {
+	ideas := quac.GetAllIdeas()
+	sort.Slice(ideas, func(i, j int) bool { return ideas[i].Id < ideas[j].Id })
+
+IDEAS:
+	for _, idea := range ideas {
+		for _, tag := range tags {
+			if !containsString(idea.Tags, tag) {
+				continue IDEAS
+			}
+		}
+		fp, found := quac.GetFilepathByID(idea.Id)
+		if !found || !strings.Contains(fp, "songsheet") {
+			continue
+		}
+		content, err := ioutil.ReadFile(fp)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, songsheetSource{strconv.Itoa(int(idea.Id)), content})
+	}
+	return sources, nil
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// songTitle returns the full title of the song (both title lines)
+func songTitle(hc headerContentFilled) string {
+	title := strings.TrimSpace(hc.title)
+	if line2 := strings.TrimSpace(hc.titleLine2); line2 != "" {
+		title += " " + line2
+	}
+	return title
+}
+
+type bookEntry struct {
+	title  string
+	pageNo int
+}
+
+func bookCmd(cmd *cobra.Command, args []string) error {
+
+	if numColumnsFlag < 1 {
+		return errors.New("numColumnsFlag must be greater than 1")
+	}
+
+	page, err := getPageLayout(cmd)
+	if err != nil {
+		return err
+	}
+
+	sources, err := resolveSongsheets(args)
+	if err != nil {
+		return err
+	}
+	var songs []songsheet
+	for _, src := range sources {
+		take, err := contentTake(src.content)
+		if err != nil {
+			return fmt.Errorf("songsheet %v: %v", src.name, err)
+		}
+		ss, err := parseSongsheet(src.content, take)
+		if err != nil {
+			return fmt.Errorf("songsheet %v: %v", src.name, err)
+		}
+		songs = append(songs, ss)
+	}
+
+	pdf := page.newPdf()
+	if err := initFonts(pdf); err != nil {
+		return err
+	}
+	bnd := page.bounds()
+
+	// share the lyric font sizing between all the songs
+	// so the whole book is consistent
+	allLines := []string{}
+	for _, ss := range songs {
+		allLines = append(allLines, ss.lines...)
+	}
+	longestHumps, lyricFontPt, err = determineLyricFontPt(
+		allLines, splitBoundsIntoColumns(bnd, numColumnsFlag)[0])
+	if err != nil {
+		return err
+	}
+
+	// every song takes up exactly one page following the title
+	// page and the contents, so page numbers are known in advance
+	tocFontPt := 14.0
+	tocLineH := 1.5 * GetFontHeight(tocFontPt)
+	tocTop := bnd.top + 2*padding + 2*GetFontHeight(24)
+	tocLinesFirstPage := int((bnd.bottom-padding-tocTop)/tocLineH) + 1
+	tocLinesPerPage := int((bnd.bottom - padding - bnd.top) / tocLineH)
+	if tocLinesFirstPage < 1 || tocLinesPerPage < 1 {
+		return errors.New("page too small for the table of contents")
+	}
+	tocPages := 1
+	if len(songs) > tocLinesFirstPage {
+		tocPages += int(math.Ceil(
+			float64(len(songs)-tocLinesFirstPage) / float64(tocLinesPerPage)))
+	}
+	entries := make([]bookEntry, len(songs))
+	for i, ss := range songs {
+		entries[i] = bookEntry{songTitle(ss.hc), 2 + tocPages + i}
+	}
+
+	// page numbers on every page but the title page
+	pageW, pageH := page.dimensions()
+	pdf.SetFooterFunc(func() {
+		if pdf.PageNo() == 1 {
+			return
+		}
+		pdf.SetFont(fontFamily, "", 10)
+		str := strconv.Itoa(pdf.PageNo())
+		strW := float64(len(str)) * GetCourierFontWidthFromHeight(GetFontHeight(10))
+		pdf.Text((pageW-strW)/2, pageH-page.margin/2, str)
+	})
+
+	printBookTitlePage(pdf, bnd, bookTitleFlag, len(songs))
+
+	// table of contents in alphabetical order
+	pdf.AddPage()
+	pdf.Bookmark("Contents", 0, 0)
+	sorted := make([]bookEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return strings.ToLower(sorted[i].title) < strings.ToLower(sorted[j].title)
+	})
+	printBookContents(pdf, bnd, tocTop, tocFontPt, tocLineH,
+		tocLinesFirstPage, tocLinesPerPage, sorted)
+
+	// the songs themselves
+	for i, ss := range songs {
+		pdf.AddPage()
+		pdf.Bookmark(entries[i].title, 0, 0)
+		err := printSongsheet(pdf, bnd, ss)
+		if err != nil {
+			return fmt.Errorf("songsheet %v: %v", sources[i].name, err)
+		}
+	}
+
+	filename := fmt.Sprintf("songbook_%v.pdf", bookTitleFlag)
+	return pdf.OutputFileAndClose(filename)
+}
+
+func printBookTitlePage(pdf *gofpdf.Fpdf, bnd bounds, title string, noSongs int) {
+	width := bnd.Width() - padding
+
+	// shrink the title until it fits across the page
+	titleFont := 48.0
+	titleFontW := 0.0
+	for ; titleFont > 8; titleFont-- {
+		titleFontW = GetCourierFontWidthFromHeight(GetFontHeight(titleFont))
+		if float64(displayWidth(title))*titleFontW <= width {
+			break
+		}
+	}
+	yMid := bnd.top + (bnd.Height()-padding)/2
+	pdf.SetFont(fontFamily, "", titleFont)
+	xTitle := bnd.left + (width-float64(displayWidth(title))*titleFontW)/2
+	pdf.Text(xTitle, yMid, encodeText(title))
+
+	subtitle := fmt.Sprintf("%v songs", noSongs)
+	if noSongs == 1 {
+		subtitle = "1 song"
+	}
+	subFontW := GetCourierFontWidthFromHeight(GetFontHeight(14))
+	pdf.SetFont(fontFamily, "", 14)
+	xSub := bnd.left + (width-float64(len(subtitle))*subFontW)/2
+	pdf.Text(xSub, yMid+2*GetFontHeight(titleFont), subtitle)
+}
+
+// printBookContents prints the table of contents beginning on the current
+// page, adding pages once the provided number of lines per page is used up
+func printBookContents(pdf *gofpdf.Fpdf, bnd bounds, top, fontPt, lineH float64,
+	linesFirstPage, linesPerPage int, entries []bookEntry) {
+
+	pdf.SetFont(fontFamily, "", 24)
+	pdf.Text(bnd.left, bnd.top+padding+GetFontHeight(24), "Contents")
+
+	fontW := GetCourierFontWidthFromHeight(GetFontHeight(fontPt))
+	lineChars := int((bnd.Width() - padding) / fontW)
+	y, linesLeft := top, linesFirstPage
+	for _, entry := range entries {
+		if linesLeft == 0 {
+			pdf.AddPage()
+			y, linesLeft = bnd.top+lineH, linesPerPage
+		}
+
+		// title .......... page
+		pageStr := strconv.Itoa(entry.pageNo)
+		title := entry.title
+		maxTitle := lineChars - len(pageStr) - 2
+		if displayWidth(title) > maxTitle {
+			title = strings.TrimRight(sliceColumns(title, 0, maxTitle-3), " ") + "..."
+		}
+		dots := lineChars - displayWidth(title) - len(pageStr) - 2
+		line := title + " " + strings.Repeat(".", dots) + " " + pageStr
+
+		pdf.SetFont(fontFamily, "", fontPt)
+		pdf.Text(bnd.left, y, encodeText(line))
+		y += lineH
+		linesLeft--
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	ClickCmd = &cobra.Command{
+		Use:   "click [filepath or qu-id]",
+		Short: "write a click-track wav following the humps and playback times",
+		Long: `write a click-track (metronome) wav with one click per hump. The tempo
+follows the playback times of the sines (or the header bpm when there are
+not enough playback times) along with the tempo marks, holds, and rests,
+the same as the pt command. The start of each bar is accented and
+optionally so are the strum marks (v and ^) along the sines.`,
+		Args: cobra.ExactArgs(1),
+		RunE: clickCmd,
+	}
+
+	clickOutputFlag       string
+	clickStrumAccentsFlag bool
+)
+
+func init() {
+	ClickCmd.PersistentFlags().StringVar(
+		&clickOutputFlag, "output", "",
+		"filepath to write to (default click_[title].wav)")
+	ClickCmd.PersistentFlags().BoolVar(
+		&clickStrumAccentsFlag, "strum-accents", false,
+		"accent the strum marks (v and ^) along the sines")
+	RootCmd.AddCommand(ClickCmd)
+}
+
+const (
+	clickSampleRate = 44100
+	clickLength     = 30 * time.Millisecond
+)
+
+type click struct {
+	at       time.Duration
+	accented bool
+}
+
+func clickCmd(cmd *cobra.Command, args []string) error {
+	sources, err := resolveSongsheets(args)
+	if err != nil {
+		return err
+	}
+	take, err := contentTake(sources[0].content)
+	if err != nil {
+		return err
+	}
+	ss, err := parseSongsheet(sources[0].content, take)
+	if err != nil {
+		return err
+	}
+
+	sines := songSines(ss.elems)
+	if len(sines) == 0 {
+		return errors.New("no sines found within the songsheet")
+	}
+	songEnd := sines[len(sines)-1].end()
+
+	// each character along the sines is a quarter hump, timed as per pt
+	lasses, _ := elemLasses(ss.elems)
+	tl, _ := newCharTimeline(lasses.charWeights(), lasses.charPositions(), headerBPM(ss.hc))
+	at := func(humps float64) time.Duration {
+		i := int(math.Round(humps * charsToaHump))
+		if i >= len(tl) {
+			i = len(tl) - 1
+		}
+		return tl[i]
+	}
+
+	beatsPerBar, err := strconv.Atoi(strings.TrimSpace(ss.hc.timesigTop))
+	if err != nil || beatsPerBar < 1 {
+		beatsPerBar = 4
+	}
+
+	// one click per hump, accented at the start of each bar
+	clicks := []click{}
+	clickAt := make(map[float64]int) // hump position to click index
+	for beat := 0; float64(beat) < songEnd; beat++ {
+		clickAt[float64(beat)] = len(clicks)
+		clicks = append(clicks, click{at(float64(beat)), beat%beatsPerBar == 0})
+	}
+
+	// accent the strum marks, adding a click where they fall between beats
+	if clickStrumAccentsFlag {
+		for _, ts := range sines {
+			for _, as := range ts.sas.alongSine {
+				if as.ch != 'v' && as.ch != '^' {
+					continue
+				}
+				pos := ts.start + as.position
+				if i, found := clickAt[pos]; found {
+					clicks[i].accented = true
+					continue
+				}
+				clicks = append(clicks, click{at(pos), true})
+			}
+		}
+	}
+
+	// the clicks are kept at the playback times so the click track lines up
+	// with the recording, any clicks extrapolated before the start are dropped
+	end := at(songEnd) + clickLength
+	if end <= 0 {
+		return errors.New("the song ends before the recording starts")
+	}
+	samples := make([]float64, int(end.Seconds()*clickSampleRate)+1)
+	for _, c := range clicks {
+		addClick(samples, c.at, c.accented)
+	}
+
+	filename := clickOutputFlag
+	if filename == "" {
+		filename = fmt.Sprintf("click_%v.wav", ss.hc.title)
+	}
+	return ioutil.WriteFile(filename, writeWAV(samples, clickSampleRate), 0666)
+}
+
+// addClick mixes a short decaying tone into the samples, accented clicks
+// are louder and higher pitched
+func addClick(samples []float64, at time.Duration, accented bool) {
+	freq, amp := 1000.0, 0.5
+	if accented {
+		freq, amp = 1600.0, 0.9
+	}
+	start := int(at.Seconds() * clickSampleRate)
+	n := int(clickLength.Seconds() * clickSampleRate)
+	for i := 0; i < n && start+i < len(samples); i++ {
+		if start+i < 0 {
+			continue
+		}
+		t := float64(i) / clickSampleRate
+		decay := math.Exp(-t / (clickLength.Seconds() / 5))
+		samples[start+i] += amp * decay * math.Sin(2*math.Pi*freq*t)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	ExportCmd = &cobra.Command{
+		Use:   "export [filepath or qu-id]...",
+		Short: "export the songsheet to another format",
+		Long: `export the songsheet to another format. Formats which combine several
+songs (such as a cue sheet of a live recording) accept several songsheets.
+The lyric formats (lrc, srt, and vtt) only time the first of the lyric lines
+stacked under a sine (ex. the first verse), the others are skipped with a
+warning.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: exportCmd,
+	}
+
+	exportFormatFlag string
+	exportOutputFlag string
+	exportKeyFlag    string
+	exportWordsFlag  bool
+)
+
+func init() {
+	ExportCmd.PersistentFlags().StringVar(
+		&exportFormatFlag, "format", "",
+		"format to export to ("+strings.Join(exportFormatNames(), ", ")+")")
+	ExportCmd.PersistentFlags().StringVar(
+		&exportOutputFlag, "output", "",
+		"filepath to write to (default songsheet_[title].[ext])")
+	ExportCmd.PersistentFlags().StringVar(
+		&exportKeyFlag, "key", "",
+		"key of the song (ex. G, F#m), overrides the // KEY= directive")
+	ExportCmd.PersistentFlags().BoolVar(
+		&exportWordsFlag, "words", false,
+		"time each word of the lyrics as well (enhanced lrc)")
+	registerTakeFlag(ExportCmd)
+	RootCmd.AddCommand(ExportCmd)
+}
+
+type exportFormat struct {
+	ext    string // file extension of the exported file
+	export func(src songsheetSource, ss songsheet) (out []byte, err error)
+
+	// (optional) exports several songsheets into the one file
+	exportSongs func(srcs []songsheetSource, sss []songsheet) (out []byte, err error)
+}
+
+// NOTE all export formats must be registered here
+var exportFormats = map[string]exportFormat{
+	"midi":     {".mid", exportMIDI, nil},
+	"cue":      {".cue", exportCue, exportCueSongs},
+	"lrc":      {".lrc", exportLRC, nil},
+	"srt":      {".srt", exportSRT, nil},
+	"vtt":      {".vtt", exportVTT, nil},
+	"chordpro": {".cho", exportChordPro, nil},
+}
+
+func exportFormatNames() (names []string) {
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func exportCmd(cmd *cobra.Command, args []string) error {
+	format, found := exportFormats[strings.ToLower(exportFormatFlag)]
+	if !found {
+		return fmt.Errorf("unknown export format %q, must be one of: %v",
+			exportFormatFlag, strings.Join(exportFormatNames(), ", "))
+	}
+
+	sources, err := resolveSongsheets(args)
+	if err != nil {
+		return err
+	}
+	if len(sources) > 1 && format.exportSongs == nil {
+		return fmt.Errorf("the %v format exports a single songsheet", exportFormatFlag)
+	}
+	sss := []songsheet{}
+	for _, src := range sources {
+		take, err := contentTake(src.content)
+		if err != nil {
+			return fmt.Errorf("%v: %v", src.name, err)
+		}
+		ss, err := parseSongsheet(src.content, take)
+		if err != nil {
+			return fmt.Errorf("%v: %v", src.name, err)
+		}
+		sss = append(sss, ss)
+	}
+
+	var out []byte
+	if len(sources) > 1 {
+		out, err = format.exportSongs(sources, sss)
+	} else {
+		out, err = format.export(sources[0], sss[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	filename := exportOutputFlag
+	if filename == "" {
+		filename = fmt.Sprintf("songsheet_%v%v", sss[0].hc.title, format.ext)
+	}
+	return ioutil.WriteFile(filename, out, 0666)
+}
+
+// exportKey returns the key of the song from the flag or the KEY directive
+func exportKey(src songsheetSource) (key musicKey, found bool, err error) {
+	keyStr := exportKeyString(src)
+	if keyStr == "" {
+		return key, false, nil
+	}
+	key, err = parseKey(keyStr)
+	return key, err == nil, err
+}
+
+// exportKeyString returns the key of the song as written within the flag or
+// the KEY directive, empty if neither is provided
+func exportKeyString(src songsheetSource) string {
+	if exportKeyFlag != "" {
+		return exportKeyFlag
+	}
+	keyStr, _ := getDirective(strings.Split(string(src.content), "\n"), "KEY")
+	return keyStr
+}
@@ -12,18 +12,33 @@ import (
 
 var (
 	SongsheetFillBPM = &cobra.Command{
-		Use: "fill-bpm [filepath]",
-		Short: "add a line to the beginning of the " +
-			"songsheet (at [filepath]) with the average bpm",
+		Use:   "fill-bpm [filepath]",
+		Short: "fill in the header bpm of the songsheet (at [filepath]) from its tempo map",
+		Long: `compute the tempo map of the songsheet from the bpm between every pair of
+playback times, printing the sections of steady tempo, tempo changes, and
+rubato. The bpm of the longest steady section is written into the header
+bpm field. Optionally each sine is annotated with its local tempo as a
+"// TEMPO: N" comment above it.`,
 		Args: cobra.ExactArgs(1),
 		RunE: fillBPMCmd,
 	}
+
+	fillBPMAnnotateFlag bool
 )
 
 func init() {
+	SongsheetFillBPM.PersistentFlags().BoolVar(
+		&fillBPMAnnotateFlag, "annotate", false,
+		"annotate each sine with its local tempo")
+	registerTakeFlag(SongsheetFillBPM)
 	RootCmd.AddCommand(SongsheetFillBPM)
 }
 
+const (
+	oldBPMLinePrefix = commentPrefix + " BPM: "
+	tempoLinePrefix  = commentPrefix + " TEMPO: "
+)
+
 func fillBPMCmd(cmd *cobra.Command, args []string) error {
 	filepath := args[0]
 	if !strings.Contains(filepath, "songsheet") {
@@ -33,72 +48,102 @@ func fillBPMCmd(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	lines := strings.Split(string(content), "\n")
+	origLines := strings.Split(string(content), "\n")
+	take, err := songTake(origLines)
+	if err != nil {
+		return err
+	}
+	lines, origIndexes := deleteCommentsMapped(origLines)
+	if _, _, err := parseHeader(lines); err != nil {
+		return err
+	}
 
 	// get the list of all lines and sasses
-	lasses := getLasses(lines)
+	// and convert them into an array of characters
+	lasses := getLasses(lines, take)
+	charPoss := lasses.charPositions()
+	timer := charTimer(charPoss, defaultBPM)
 
-	// convert the sasses into an array of characters
-	type charPos struct {
-		hasPT bool
-		pt    playbackTime
+	sections := tempoMap(timer.markers)
+	bpm, found := mainBPM(sections)
+	if !found {
+		return errors.New("couldn't find two playback times to calculate bpm with")
 	}
-	charPoss := []charPos{}
-	for _, s := range lasses {
-		maxChars := int((s.sas.totalHumps() * charsToaHump) + 0.00001) // float rounding
-		for j := 0; j < maxChars; j++ {
-			cp := charPos{}
-			if s.sas.hasPlaybackTime && s.sas.ptCharPosition == j {
-				cp.hasPT = true
-				cp.pt = s.sas.pt
+	bpm, factor := normalizeBPM(bpm) // all tempos are folded the same
+	printTempoMap(sections, factor)
+
+	// annotate each sine which is within the playback times with its tempo
+	annotations := make(map[int]string) // by original line index
+	if fillBPMAnnotateFlag {
+		first, last := timer.markers[0].humps, timer.markers[len(timer.markers)-1].humps
+		startI := 0
+		for i, ls := range lasses {
+			endI := startI
+			for endI < len(charPoss) && charPoss[endI].lassesIndex == i {
+				endI++
+			}
+			start, end := float64(startI)/charsToaHump, float64(endI)/charsToaHump
+			startI = endI
+			if start < first || end > last || end <= start {
+				continue
+			}
+			minutes := (timer.at(end) - timer.at(start)).Minutes()
+			annotation := fmt.Sprintf("%v%v", tempoLinePrefix,
+				int(math.Round((end-start)/minutes*factor)))
+			if inRubato(sections, (start+end)/2) {
+				annotation += " rubato"
 			}
-			charPoss = append(charPoss, cp)
+			annotations[origIndexes[int(ls.lineNo)]] = annotation
 		}
 	}
 
-	// get the first and last playback times
-	var ptFirst, ptLast playbackTime
-	ptFirstFound, ptLastFound := false, false
-	unusedChars := 0
-	for i := 0; i < len(charPoss); i++ {
-		cp := charPoss[i]
-		if cp.hasPT {
-			ptFirst = cp.pt
-			ptFirstFound = true
-			break
+	// write the bpm into the header (under DATE: after the time signature)
+	datePos := displayWidth(strings.SplitN(lines[0], "DATE:", 2)[0])
+	bpmLineIndex := origIndexes[1]
+
+	bpmStr := fmt.Sprintf("%v", int(math.Round(bpm)))
+	out := []string{}
+	for i, line := range origLines {
+		if strings.HasPrefix(line, oldBPMLinePrefix) || strings.HasPrefix(line, tempoLinePrefix) {
+			continue // replaced by the header bpm and new annotations
 		}
-		unusedChars++
-	}
-	for i := len(charPoss) - 1; i >= 0; i-- {
-		cp := charPoss[i]
-		if cp.hasPT {
-			ptLast = cp.pt
-			ptLastFound = true
-			break
+		if annotation, found := annotations[i]; found {
+			out = append(out, annotation)
 		}
-		unusedChars++
-	}
-	if !ptFirstFound || !ptLastFound {
-		err := fmt.Errorf("//ERROR: couldn't find two playback times to calculate bpm with")
-		content = []byte(err.Error() + "\n" + string(content))
-		err = ioutil.WriteFile(filepath, content, 0666)
-		return err
+		if i == bpmLineIndex {
+			line = replaceColumns(line, datePos+2, datePos+5, fmt.Sprintf("%-3v", bpmStr))
+		}
+		out = append(out, line)
 	}
 
-	// calculate the bpm
-	minutes := ptLast.t.Sub(ptFirst.t).Minutes()
-	beats := float64(len(charPoss)-unusedChars) / charsToaHump
-	bpm := beats / minutes
-	if bpm < 50 { // some normalization
-		bpm *= 2
+	// keep any bpm directive up to date along with the header
+	if _, found := getDirective(out, "BPM"); found {
+		out = setDirective(out, "BPM", bpmStr)
 	}
-	if bpm > 200 { // some normalization
-		bpm /= 2
+	return writeSongsheet(filepath, out)
+}
+
+// inRubato returns whether the position (in humps) is within a rubato section
+func inRubato(sections []tempoSection, humps float64) bool {
+	for _, sec := range sections {
+		if sec.rubato && humps >= sec.start.humps && humps <= sec.end.humps {
+			return true
+		}
 	}
+	return false
+}
 
-	// write the bpm line
-	newLine := fmt.Sprintf("// BPM: %v", int(math.Round(bpm)))
-	content = []byte(newLine + "\n" + string(content))
-	err = ioutil.WriteFile(filepath, content, 0666)
-	return nil
+// printTempoMap prints each section of the tempo map
+func printTempoMap(sections []tempoSection, factor float64) {
+	for i, sec := range sections {
+		desc := "steady"
+		switch {
+		case sec.rubato:
+			desc = "rubato"
+		case i > 0 && !sections[i-1].rubato:
+			desc = "tempo change"
+		}
+		fmt.Printf("%v - %v  %3v bpm  %v\n", sec.start.pt.str, sec.end.pt.str,
+			int(math.Round(sec.bpm()*factor)), desc)
+	}
 }
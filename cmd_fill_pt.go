@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	FillPTCmd = &cobra.Command{
+		Use:   "fill-pt [filepath]",
+		Short: "fill in the playback time line under every sine",
+		Long: `insert or update the playback time line (mm:ss.cs) under every sine of the
+songsheet, timed as per the pt command. With --start and/or --bpm every sine
+is timed from the start time at the bpm (default the header bpm). Otherwise
+the playback times already within the songsheet are kept as anchors and
+the remaining sines are timed from them.`,
+		Args: cobra.ExactArgs(1),
+		RunE: fillPTCmd,
+	}
+
+	fillPTStartFlag string
+	fillPTBPMFlag   float64
+)
+
+func init() {
+	FillPTCmd.PersistentFlags().StringVar(
+		&fillPTStartFlag, "start", "00:00.00",
+		"playback time of the first hump (mm:ss.cs)")
+	FillPTCmd.PersistentFlags().Float64Var(
+		&fillPTBPMFlag, "bpm", 0,
+		"bpm of the humps (default the header bpm)")
+	registerTakeFlag(FillPTCmd)
+	RootCmd.AddCommand(FillPTCmd)
+}
+
+func fillPTCmd(cmd *cobra.Command, args []string) error {
+	filepath := args[0]
+	content, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return err
+	}
+	origLines := strings.Split(string(content), "\n")
+	take, err := songTake(origLines)
+	if err != nil {
+		return err
+	}
+	lines, origIndexes := deleteCommentsMapped(origLines)
+
+	// get the list of all lines and sasses
+	// and convert them into an array of characters
+	lasses := getLasses(lines, take)
+	if len(lasses) == 0 {
+		return errors.New("no sines found within the songsheet")
+	}
+	charPoss := lasses.charPositions()
+	bpm := linesBPM(lines)
+
+	// time the characters either from the start at the bpm
+	// or from the existing playback times
+	fromStart := cmd.Flags().Changed("start") || cmd.Flags().Changed("bpm")
+	var offset playbackTime
+	if fromStart {
+		var found bool
+		offset, _, found = getPlaybackTimeFromLine(fillPTStartFlag)
+		if !found {
+			return fmt.Errorf("could not parse playback time: %v", fillPTStartFlag)
+		}
+		if fillPTBPMFlag < 0 {
+			return fmt.Errorf("bad bpm: %v", fillPTBPMFlag)
+		}
+		if fillPTBPMFlag > 0 {
+			bpm = fillPTBPMFlag
+		}
+		for i := range charPoss {
+			charPoss[i].hasPT = false
+		}
+	}
+	tl, anchored := newCharTimeline(lasses.charWeights(), charPoss, bpm)
+	if !fromStart && !anchored {
+		return errors.New("no playback times to anchor to, provide --start and/or --bpm")
+	}
+
+	// determine the playback time line of each sine by original line index
+	inserts := make(map[int]string)  // lines to insert after
+	replaces := make(map[int]string) // lines to replace
+	charI := 0
+	for i, ls := range lasses {
+		firstCharI := charI
+		for charI < len(charPoss) && charPoss[charI].lassesIndex == i {
+			charI++
+		}
+		if !fromStart && ls.sas.hasPlaybackTime() {
+			continue // keep the anchors as they are
+		}
+		ptAt := func(charPosition int) playbackTime {
+			ci := firstCharI + charPosition
+			if ci > charI {
+				ci = charI // beyond the end of the sine
+			}
+			d := tl[ci]
+			if fromStart {
+				d += offset.elapsed()
+			}
+			return playbackTime{}.AddDur(d)
+		}
+
+		// retime each of the existing playback times at its own position
+		if ls.sas.hasPlaybackTime() {
+			pts := []sinePlaybackTime{}
+			for _, spt := range ls.sas.playbackTimes {
+				pts = append(pts, sinePlaybackTime{ptAt(spt.charPosition), spt.charPosition})
+			}
+			replaces[origIndexes[int(ls.lineNo)+ls.sas.ptLineOffset]] =
+				formatTakePlaybackTimesLine(take, pts)
+			continue
+		}
+		if pt := ptAt(0); pt.elapsed() >= 0 { // otherwise before the recording starts
+			// after the playback times of any other takes
+			inserts[origIndexes[int(ls.lineNo)+3+ls.sas.ptLines]] =
+				formatTakePlaybackTimesLine(take, []sinePlaybackTime{{pt, 0}})
+		}
+	}
+
+	out := []string{}
+	for i, line := range origLines {
+		if ptLine, found := replaces[i]; found {
+			line = keepComment(line, ptLine)
+		}
+		out = append(out, line)
+		if ptLine, found := inserts[i]; found {
+			out = append(out, ptLine)
+		}
+	}
+	return writeSongsheet(filepath, out)
+}
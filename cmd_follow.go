@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	FollowCmd = &cobra.Command{
+		Use:   "follow [filepath] [cursor-x] [cursor-y]",
+		Short: "stream the cursor positions (line col) along the sines at tempo",
+		Long: `starting from the cursor position, print the position (line col) of each
+character along the sines as it's reached, timed from the playback times
+(or the header bpm when there are not enough playback times). Positions
+which have already passed (if the output is slow) are skipped so the
+cursor never drifts behind. END is printed once the last sine finishes.`,
+		Args: cobra.ExactArgs(3),
+		RunE: followCmd,
+	}
+
+	followKeysFlag bool
+)
+
+func init() {
+	FollowCmd.PersistentFlags().BoolVar(
+		&followKeysFlag, "keys", false,
+		"print vim key sequences to move the cursor rather than line col")
+	registerTakeFlag(FollowCmd)
+	RootCmd.AddCommand(FollowCmd)
+}
+
+func followCmd(cmd *cobra.Command, args []string) error {
+
+	// get the relevant file
+	content, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	curX, err := strconv.Atoi(args[1])
+	if err != nil {
+		return err
+	}
+	curY, err := strconv.Atoi(args[2])
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	take, err := songTake(lines)
+	if err != nil {
+		return err
+	}
+	lines, origIndexes := deleteCommentsMapped(lines)
+	curY = strippedLineNo(origIndexes, curY)
+
+	// get the list of all lines and sasses
+	// and convert them into an array of characters
+	lasses := getLasses(lines, take)
+	charPoss := lasses.charPositions()
+	if len(charPoss) == 0 {
+		fmt.Println("END")
+		return nil
+	}
+	startI := lasses.cursorCharIndex(charPoss, curX, curY)
+	tl, _ := newCharTimeline(lasses.charWeights(), charPoss, linesBPM(lines))
+
+	// each position is scheduled from the start rather than from the
+	// previous position so the sleeps don't accumulate drift
+	start := time.Now()
+	untilChar := func(i int) time.Duration {
+		return time.Until(start.Add(tl[i] - tl[startI]))
+	}
+	for i := startI; i < len(charPoss); i++ {
+
+		// skip any positions which have already passed
+		if i+1 < len(charPoss) && untilChar(i+1) <= 0 {
+			continue
+		}
+		time.Sleep(untilChar(i))
+
+		cp := charPoss[i]
+		lineNo := int(lasses[cp.lassesIndex].lineNo) + lineNoOffsetToMiddleHump
+		lineNo = originalLineNo(origIndexes, lineNo)
+		if followKeysFlag {
+			fmt.Printf("%vgg%v|\n", lineNo, cp.col+1)
+		} else {
+			fmt.Printf("%v %v\n", lineNo, cp.col+1)
+		}
+	}
+
+	// wait for the last character to finish
+	time.Sleep(untilChar(len(charPoss)))
+	fmt.Println("END")
+	return nil
+}
@@ -26,6 +26,7 @@ var (
 	spacingRatioFlag       float64
 	sineAmplitudeRatioFlag float64
 	numColumnsFlag         uint16
+	waveformFlag           bool
 
 	subsupSizeMul = 0.65 // size of sub and superscript relative to thier root's size
 )
@@ -43,30 +44,31 @@ func init() {
 	GenerateCmd.PersistentFlags().Float64Var(
 		&sineAmplitudeRatioFlag, "amp-ratio", 0.8,
 		"ratio of amplitude of the sine curve to the lyric text")
+	GenerateCmd.PersistentFlags().StringVar(
+		&fontFlag, "font", "",
+		"filepath to a monospaced UTF-8 (.ttf) font for non-latin text")
+	GenerateCmd.PersistentFlags().StringVar(
+		&fontBoldFlag, "font-bold", "",
+		"filepath to the bold variant of the UTF-8 font")
+	GenerateCmd.PersistentFlags().BoolVar(
+		&waveformFlag, "waveform", false,
+		"draw the amplitude of the linked wav audio behind each sine (aligned by the playback times)")
+	registerTakeFlag(GenerateCmd)
+	registerPageFlags(GenerateCmd)
 	RootCmd.AddCommand(GenerateCmd)
 }
 
 func genCmd(cmd *cobra.Command, args []string) error {
 
-	pdf := gofpdf.New("P", "in", "Letter", "")
-	pdf.SetMargins(0, 0, 0)
-	pdf.AddPage()
-
-	// each line of text from the input file
-	// is attempted to be fit into elements
-	// in the order provided within elemKinds
-	elemKinds := []tssElement{
-		spacer{},
-		chordChart{},
-		sine{},
-		melodies{},
-		lyrics{},
-	}
-
 	if numColumnsFlag < 1 {
 		return errors.New("numColumnsFlag must be greater than 1")
 	}
 
+	page, err := getPageLayout(cmd)
+	if err != nil {
+		return err
+	}
+
 	quid, err := strconv.Atoi(args[0])
 	if err != nil {
 		return err
@@ -76,56 +78,142 @@ func genCmd(cmd *cobra.Command, args []string) error {
 	if !found {
 		return fmt.Errorf("could not find anything under id: %v", quid)
 	}
-	lines := strings.Split(string(content), "\n")
-	lines = deleteComments(lines)
-
-	// get the header
-	lines, hc, err := parseHeader(lines)
-	filename := fmt.Sprintf("songsheet_%v.pdf", hc.title)
-
-	bnd := bounds{padding, padding, 11, 8.5}
-	if printTitleFlag {
-		hc.title = ""
+	origLines := strings.Split(string(content), "\n")
+	take, err := songTake(origLines)
+	if err != nil {
+		return err
 	}
-	bnd = printHeaderFilled(pdf, bnd, &hc)
+	ss, err := parseSongsheet(content, take)
+	if err != nil {
+		return err
+	}
+	if waveformFlag {
+		if err := addWaveforms(&ss, origLines); err != nil {
+			return err
+		}
+	}
+	filename := fmt.Sprintf("songsheet_%v.pdf", ss.hc.title)
 
-	//seperate out remaining bounds into columns
-	bndsColsIndex := 0
-	bndsCols := splitBoundsIntoColumns(bnd, numColumnsFlag)
-	if len(bndsCols) == 0 {
-		panic("no bound columns")
+	pdf := page.newPdf()
+	if err := initFonts(pdf); err != nil {
+		return err
 	}
 
 	//determine lyricFontPt
-	longestHumps, lyricFontPt, err = determineLyricFontPt(lines, bndsCols[0])
+	bnd := page.bounds()
+	longestHumps, lyricFontPt, err = determineLyricFontPt(
+		ss.lines, splitBoundsIntoColumns(bnd, numColumnsFlag)[0])
+	if err != nil {
+		return err
+	}
+
+	err = printSongsheet(pdf, bnd, ss)
 	if err != nil {
 		return err
 	}
+	return pdf.OutputFileAndClose(filename)
+}
+
+// songsheet is the parsed contents of a songsheet file
+type songsheet struct {
+	hc        headerContentFilled
+	take      string       // the take whose playback times were parsed
+	lines     []string     // lines of the songsheet body (without comments)
+	elems     []tssElement // elements parsed from the lines
+	elemLines []int        // original line index (with comments) of each element
+}
+
+// parseSongsheet parses the songsheet with the playback times of the take
+func parseSongsheet(content []byte, take string) (ss songsheet, err error) {
+	allLines, origIndexes := deleteCommentsMapped(strings.Split(string(content), "\n"))
+
+	// get the header
+	lines, hc, err := parseHeader(allLines)
+	ss.hc = hc
+	if err != nil {
+		return ss, err
+	}
+	ss.lines = lines
+	ss.take = take
+	headerLen := len(allLines) - len(lines)
 
 	// get contents of songsheet
-	// parse all the elems
-	parsedElems := []tssElement{}
+	elems, starts, err := parseElems(lines, take)
+	ss.elems = elems
+	for _, start := range starts {
+		ss.elemLines = append(ss.elemLines, origIndexes[headerLen+start])
+	}
+	return ss, err
+}
+
+// elemsParseError is returned when no element kind can parse the line
+type elemsParseError struct {
+	lineIndex int // index of the line which could not be parsed
+	lines     []string
+	allErrs   []string
+}
+
+func (e elemsParseError) Error() string {
+	return fmt.Sprintf("could not parse song at line %+v\n all errors%+v\n", e.lines, e.allErrs)
+}
+
+// parseElems parses all the elems of the songsheet body, also returning
+// the index of the line which each element starts at. The playback times
+// parsed are those of the take.
+func parseElems(lines []string, take string) (elems []tssElement, starts []int, err error) {
+
+	// each line of text from the input file
+	// is attempted to be fit into elements
+	// in the order provided within elemKinds
+	elemKinds := []tssElement{
+		spacer{},
+		chordChart{},
+		sine{take: take},
+		melodies{},
+		lyrics{},
+	}
 
+	total := len(lines)
 OUTER:
 	if len(lines) > 0 {
 		allErrs := []string{}
 		for _, elem := range elemKinds {
 			reduced, newElem, err := elem.parseText(lines)
 			if err == nil {
+				starts = append(starts, total-len(lines))
 				lines = reduced
-				parsedElems = append(parsedElems, newElem)
+				elems = append(elems, newElem)
 				goto OUTER
 			} else {
 				allErrs = append(allErrs, err.Error())
 			}
 		}
-		return fmt.Errorf("could not parse song at line %+v\n all errors%+v\n", lines, allErrs)
+		return elems, starts, elemsParseError{total - len(lines), lines, allErrs}
+	}
+	return elems, starts, nil
+}
+
+// printSongsheet prints the songsheet onto the current page of the pdf,
+// lyricFontPt and longestHumps must already be determined
+func printSongsheet(pdf *gofpdf.Fpdf, bnd bounds, ss songsheet) error {
+
+	hc := ss.hc
+	if printTitleFlag {
+		hc.title = ""
+	}
+	bnd = printHeaderFilled(pdf, bnd, &hc)
+
+	//seperate out remaining bounds into columns
+	bndsColsIndex := 0
+	bndsCols := splitBoundsIntoColumns(bnd, numColumnsFlag)
+	if len(bndsCols) == 0 {
+		panic("no bound columns")
 	}
 
 	// print the songsheet elements
 	//  - use a dummy pdf to test whether the borders are exceeded within
 	//    the current column, if so move to the next column
-	for _, el := range parsedElems {
+	for _, el := range ss.elems {
 		dummy := dummyPdf{}
 		bndNew := el.printPDF(dummy, bndsCols[bndsColsIndex])
 		if bndNew.Height() < padding/2 {
@@ -137,8 +225,7 @@ OUTER:
 		}
 		bndsCols[bndsColsIndex] = el.printPDF(pdf, bndsCols[bndsColsIndex])
 	}
-
-	return pdf.OutputFileAndClose(filename)
+	return nil
 }
 
 func splitBoundsIntoColumns(bnd bounds, numCols uint16) (splitBnds []bounds) {
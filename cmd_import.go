@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	ImportCmd = &cobra.Command{
+		Use:   "import",
+		Short: "import a song from another format into a new songsheet",
+	}
+
+	ImportChordProCmd = &cobra.Command{
+		Use:   "chordpro [filepath]",
+		Short: "import a ChordPro chord sheet into a new songsheet",
+		Long: `convert a ChordPro chord sheet into a first draft songsheet. The title,
+subtitle (or artist), capo, tempo, and time directives fill the header, the
+key becomes a // KEY= directive, and each section (ex. {start_of_chorus})
+becomes a // SECTION= directive. Each line with [chords] becomes a sine
+sized to the line with the chords along its axis and the lyrics under it.
+Comments and all other directives are kept as // comments.`,
+		Args: cobra.ExactArgs(1),
+		RunE: importChordProCmd,
+	}
+
+	importOutputFlag string
+)
+
+func init() {
+	ImportChordProCmd.PersistentFlags().StringVar(
+		&importOutputFlag, "output", "",
+		"filepath to write to (default songsheet_[title], lowercased with dashes)")
+	ImportCmd.AddCommand(ImportChordProCmd)
+	RootCmd.AddCommand(ImportCmd)
+}
+
+func importChordProCmd(cmd *cobra.Command, args []string) error {
+	content, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	base := filepath.Base(args[0])
+	defaultTitle := strings.TrimSuffix(base, filepath.Ext(base))
+	lines, title := importChordPro(string(content), defaultTitle, time.Now())
+
+	path := importOutputFlag
+	if path == "" {
+		name := fileSafe(title)
+		if name == "" {
+			name = fileSafe(defaultTitle)
+		}
+		if name == "" {
+			name = "imported"
+		}
+		path = "songsheet_" + name
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%v already exists, choose another --output", path)
+	}
+	if err := writeSongsheet(path, lines); err != nil {
+		return err
+	}
+	fmt.Println(path)
+	return nil
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	ImportLRCCmd = &cobra.Command{
+		Use:   "import-lrc [filepath] [lrc-filepath]",
+		Short: "write the playback times of the lyrics from an lrc file",
+		Long: `match the timestamped lines of the lrc file to the lyric lines of the
+songsheet (in order, with fuzzy text matching) and write the timestamp of
+each matched line as a playback time under the sine above the lyric, at the
+column where the lyric starts. Playback times already under the sines are
+kept unless at the same column. The lrc lines and lyrics which couldn't be
+matched are printed.`,
+		Args: cobra.ExactArgs(2),
+		RunE: importLRCCmd,
+	}
+
+	importLRCMinSimilarityFlag float64
+	importLRCDryRunFlag        bool
+)
+
+func init() {
+	ImportLRCCmd.PersistentFlags().Float64Var(
+		&importLRCMinSimilarityFlag, "min-similarity", 0.6,
+		"minimum text similarity (0 to 1) for an lrc line to match a lyric")
+	ImportLRCCmd.PersistentFlags().BoolVar(
+		&importLRCDryRunFlag, "dry-run", false,
+		"only print the matches")
+	registerTakeFlag(ImportLRCCmd)
+	RootCmd.AddCommand(ImportLRCCmd)
+}
+
+// lrcLine is a timestamped line of an lrc file
+type lrcLine struct {
+	t    time.Duration
+	text string
+}
+
+var (
+	lrcTimeTagRe = regexp.MustCompile(`^\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+	lrcWordTagRe = regexp.MustCompile(`<\d+:\d{1,2}(?:[.:]\d{1,3})?>`)
+)
+
+// parseLRC returns the timestamped lines of the lrc content in order of time,
+// metadata tags are ignored as are the word timestamps of enhanced lrc
+func parseLRC(content string) (lls []lrcLine) {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		times := []time.Duration{}
+		for {
+			m := lrcTimeTagRe.FindStringSubmatch(line)
+			if m == nil {
+				break
+			}
+			min, _ := strconv.Atoi(m[1])
+			sec, _ := strconv.Atoi(m[2])
+			t := time.Duration(min)*time.Minute + time.Duration(sec)*time.Second
+			if m[3] != "" {
+				frac, _ := strconv.Atoi(m[3])
+				for i := len(m[3]); i < 3; i++ {
+					frac *= 10 // as milliseconds
+				}
+				t += time.Duration(frac) * time.Millisecond
+			}
+			times = append(times, t)
+			line = line[len(m[0]):]
+		}
+		text := strings.TrimSpace(lrcWordTagRe.ReplaceAllString(line, ""))
+		for _, t := range times {
+			if text != "" {
+				lls = append(lls, lrcLine{t, text})
+			}
+		}
+	}
+	sort.SliceStable(lls, func(i, j int) bool { return lls[i].t < lls[j].t })
+	return lls
+}
+
+// songLyric is a lyric line of the songsheet along with the sine above it
+type songLyric struct {
+	text     string
+	lineNo   int // of the lines with comments deleted
+	col      int // display column where the lyric starts
+	lassesI  int // the sine above
+	matchedI int // index of the matching lrc line, -1 if none
+}
+
+func importLRCCmd(cmd *cobra.Command, args []string) error {
+	filepath := args[0]
+	content, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return err
+	}
+	lrcContent, err := ioutil.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+	lls := parseLRC(string(lrcContent))
+	if len(lls) == 0 {
+		return errors.New("no timestamped lines found within the lrc file")
+	}
+
+	origLines := strings.Split(string(content), "\n")
+	take, err := songTake(origLines)
+	if err != nil {
+		return err
+	}
+	lines, origIndexes := deleteCommentsMapped(origLines)
+	body, _, err := parseHeader(lines)
+	if err != nil {
+		return err
+	}
+	headerLen := len(lines) - len(body)
+	elems, starts, err := parseElems(body, take)
+	if err != nil {
+		return err
+	}
+
+	// the lyrics under the sines
+	lasses := getLasses(lines, take)
+	lassesByLine := make(map[int]int)
+	for i, ls := range lasses {
+		lassesByLine[int(ls.lineNo)] = i
+	}
+	songLyrics := []songLyric{}
+	lasI := -1
+	for i, el := range elems {
+		lineNo := headerLen + starts[i]
+		switch el := el.(type) {
+		case sine:
+			if i, found := lassesByLine[lineNo]; found {
+				lasI = i
+			}
+		case lyrics:
+			trimmed := strings.TrimLeft(el.lyrics, " ")
+			if lasI < 0 || strings.TrimSpace(trimmed) == "" {
+				continue
+			}
+			col := displayWidth(el.lyrics) - displayWidth(trimmed)
+			songLyrics = append(songLyrics, songLyric{strings.TrimSpace(trimmed), lineNo, col, lasI, -1})
+		}
+	}
+	if len(songLyrics) == 0 {
+		return errors.New("no lyrics under the sines to match")
+	}
+	matchLyrics(songLyrics, lls, importLRCMinSimilarityFlag)
+
+	// the new playback times of each sine by column
+	newPTs := make(map[int]map[int]playbackTime) // by lasses index then column
+	for _, sl := range songLyrics {
+		if sl.matchedI < 0 {
+			fmt.Printf("unmatched lyric (line %v): %v\n", originalLineNo(origIndexes, sl.lineNo+1), sl.text)
+			continue
+		}
+		if newPTs[sl.lassesI] == nil {
+			newPTs[sl.lassesI] = make(map[int]playbackTime)
+		}
+		if _, found := newPTs[sl.lassesI][sl.col]; found {
+			continue // stacked lyrics (ex. verses) are timed by the first
+		}
+		ll := lls[sl.matchedI]
+		newPTs[sl.lassesI][sl.col] = playbackTime{}.AddDur(ll.t)
+	}
+	matched := make(map[int]bool)
+	for _, sl := range songLyrics {
+		matched[sl.matchedI] = true
+	}
+	for i, ll := range lls {
+		if !matched[i] {
+			fmt.Printf("unmatched lrc line [%v]: %v\n", lrcTime(ll.t), ll.text)
+		}
+	}
+
+	inserts := make(map[int]string)  // lines to insert after
+	replaces := make(map[int]string) // lines to replace
+	for lasI, ls := range lasses {
+		byCol, found := newPTs[lasI]
+		if !found {
+			continue
+		}
+		// the imported playback times must fit between the kept playback
+		// times on the line (with a space between each)
+		kept := []sinePlaybackTime{}
+		for _, spt := range ls.sas.playbackTimes {
+			if _, found := byCol[spt.charPosition]; !found {
+				kept = append(kept, spt)
+			}
+		}
+		cols := []int{}
+		for col := range byCol {
+			cols = append(cols, col)
+		}
+		sort.Ints(cols)
+		for _, col := range cols {
+			spt := sinePlaybackTime{byCol[col], col}
+			if ptsOverlap(kept, spt) {
+				fmt.Printf("skipped %v (line %v), too close to another playback time\n",
+					spt.pt.str, originalLineNo(origIndexes, int(ls.lineNo)+1))
+				continue
+			}
+			kept = append(kept, spt)
+		}
+		sort.Slice(kept, func(i, j int) bool { return kept[i].charPosition < kept[j].charPosition })
+		for _, spt := range kept {
+			fmt.Printf("line %v: %v at column %v\n",
+				originalLineNo(origIndexes, int(ls.lineNo)+1), spt.pt.str, spt.charPosition+1)
+		}
+
+		ptLine := formatTakePlaybackTimesLine(take, kept)
+		if ls.sas.hasPlaybackTime() {
+			replaces[origIndexes[int(ls.lineNo)+ls.sas.ptLineOffset]] = ptLine
+			continue
+		}
+		inserts[origIndexes[int(ls.lineNo)+3+ls.sas.ptLines]] = ptLine
+	}
+	if importLRCDryRunFlag {
+		return nil
+	}
+
+	out := []string{}
+	for i, line := range origLines {
+		if ptLine, found := replaces[i]; found {
+			line = keepComment(line, ptLine)
+		}
+		out = append(out, line)
+		if ptLine, found := inserts[i]; found {
+			out = append(out, ptLine)
+		}
+	}
+	return writeSongsheet(filepath, out)
+}
+
+// ptsOverlap returns whether the playback time would overlap (or touch) any
+// of the playback times on the line
+func ptsOverlap(pts []sinePlaybackTime, spt sinePlaybackTime) bool {
+	start, end := spt.charPosition, spt.charPosition+displayWidth(spt.pt.str)
+	for _, other := range pts {
+		otherEnd := other.charPosition + displayWidth(other.pt.str)
+		if start <= otherEnd && other.charPosition <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLyrics aligns the lrc lines to the song lyrics, both in order, so
+// that the total similarity of the matches is greatest. Repeated lyrics
+// (ex. a chorus written out once) only match once.
+func matchLyrics(sls []songLyric, lls []lrcLine, minSimilarity float64) {
+	n, m := len(sls), len(lls)
+	score := make([][]float64, n+1)
+	for i := range score {
+		score[i] = make([]float64, m+1)
+	}
+	sim := func(i, j int) float64 { return textSimilarity(sls[i].text, lls[j].text) }
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			best := score[i+1][j]
+			if score[i][j+1] > best {
+				best = score[i][j+1]
+			}
+			if s := sim(i, j); s >= minSimilarity && score[i+1][j+1]+s > best {
+				best = score[i+1][j+1] + s
+			}
+			score[i][j] = best
+		}
+	}
+
+	// follow the best alignment
+	for i, j := 0, 0; i < n && j < m; {
+		s := sim(i, j)
+		switch {
+		case s >= minSimilarity && score[i][j] == score[i+1][j+1]+s:
+			sls[i].matchedI = j
+			i, j = i+1, j+1
+		case score[i][j] == score[i+1][j]:
+			i++
+		default:
+			j++
+		}
+	}
+}
+
+// textSimilarity scores (0 to 1) how alike the texts are, ignoring case,
+// punctuation, and spacing
+func textSimilarity(a, b string) float64 {
+	ra, rb := normalizeLyric(a), normalizeLyric(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func normalizeLyric(s string) (out []rune) {
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// levenshtein returns the edit distance between the runes
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLRC(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []lrcLine
+	}{
+		{
+			name:    "centiseconds and milliseconds",
+			content: "[00:01.50]one\n[01:02.250]two",
+			want: []lrcLine{
+				{1500 * time.Millisecond, "one"},
+				{62250 * time.Millisecond, "two"},
+			},
+		},
+		{
+			name:    "metadata and blank lines ignored",
+			content: "[ti:Song]\n[ar:Someone]\n\n[00:03]three\n[00:04.00]",
+			want:    []lrcLine{{3 * time.Second, "three"}},
+		},
+		{
+			name:    "repeated lines in order of time",
+			content: "[00:09.00]chorus\n[00:01.00][00:05.00]refrain",
+			want: []lrcLine{
+				{time.Second, "refrain"},
+				{5 * time.Second, "refrain"},
+				{9 * time.Second, "chorus"},
+			},
+		},
+		{
+			name:    "enhanced word times removed",
+			content: "[00:02.00]<00:02.00>word <00:02.50>by word <00:03.00>",
+			want:    []lrcLine{{2 * time.Second, "word by word"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := parseLRC(tc.content)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("line %v: got %v, want %v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMatchLyrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		lyrics []string
+		lrc    []string
+		want   []int // matching lrc line of each lyric, -1 if none
+	}{
+		{
+			name:   "same lines",
+			lyrics: []string{"hello world", "second line"},
+			lrc:    []string{"Hello, world!", "second line"},
+			want:   []int{0, 1},
+		},
+		{
+			name:   "lrc line inserted",
+			lyrics: []string{"hello world", "second line", "last line"},
+			lrc:    []string{"hello world", "an instrumental break", "second line", "last line"},
+			want:   []int{0, 2, 3},
+		},
+		{
+			name:   "lyric skipped by the lrc",
+			lyrics: []string{"hello world", "only in the songsheet", "last line"},
+			lrc:    []string{"hello world", "last line"},
+			want:   []int{0, -1, 1},
+		},
+		{
+			name:   "repeated chorus written out once",
+			lyrics: []string{"verse words", "chorus words"},
+			lrc:    []string{"verse words", "chorus words", "chorus words"},
+			want:   []int{0, 1},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sls := []songLyric{}
+			for _, text := range tc.lyrics {
+				sls = append(sls, songLyric{text: text, matchedI: -1})
+			}
+			lls := []lrcLine{}
+			for i, text := range tc.lrc {
+				lls = append(lls, lrcLine{time.Duration(i) * time.Second, text})
+			}
+			matchLyrics(sls, lls, 0.6)
+			for i, sl := range sls {
+				if sl.matchedI != tc.want[i] {
+					t.Errorf("lyric %q: got lrc line %v, want %v", sl.text, sl.matchedI, tc.want[i])
+				}
+			}
+		})
+	}
+}
@@ -54,6 +54,11 @@ func init() {
 		&headerFlag, "header", true, "include a header element")
 	PaperCmd.PersistentFlags().BoolVar(
 		&mirrorStringsOrderFlag, "mirror", false, "mirror string positions")
+	PaperCmd.PersistentFlags().StringVar(
+		&fontFlag, "font", "", "filepath to a monospaced UTF-8 (.ttf) font for non-latin text")
+	PaperCmd.PersistentFlags().StringVar(
+		&fontBoldFlag, "font-bold", "", "filepath to the bold variant of the UTF-8 font")
+	registerPageFlags(PaperCmd)
 	RootCmd.AddCommand(PaperCmd)
 }
 
@@ -62,9 +67,14 @@ func init() {
 
 func paperCmd(cmd *cobra.Command, args []string) error {
 
-	pdf := gofpdf.New("P", "in", "Letter", "")
-	pdf.SetMargins(0, 0, 0)
-	pdf.AddPage()
+	page, err := getPageLayout(cmd)
+	if err != nil {
+		return err
+	}
+	pdf := page.newPdf()
+	if err := initFonts(pdf); err != nil {
+		return err
+	}
 
 	elem, err := parseElem(args[0])
 	if err != nil {
@@ -74,7 +84,7 @@ func paperCmd(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("could not parse %v", args[0])
 	}
 
-	bnd := bounds{padding, padding, 11, 8.5}
+	bnd := page.bounds()
 	if headerFlag {
 		bnd = printHeader(pdf, bnd, nil)
 	}
@@ -142,29 +152,25 @@ type headerContent struct {
 }
 
 func printHeader(pdf *gofpdf.Fpdf, bnd bounds, hc *headerContent) (reducedBounds bounds) {
-	dateRightOffset := 2.3
+	// the header is laid out for a letter page, shrink it for narrower pages
+	scale := headerScale(bnd)
+	dateRightOffset := 2.3 * scale
 	totalHeaderHeight := 1.0
 	boxHeight := 0.25
 	boxTextMargin := 0.06
 
 	// flip string orientation if called for
 	if mirrorStringsOrderFlag {
-		thicknessesRev := make([]float64, len(thicknesses))
-		j := len(thicknesses) - 1
-		for i := 0; i < len(thicknesses); i++ {
-			thicknessesRev[j] = thicknesses[i]
-			j--
-		}
-		thicknesses = thicknessesRev
+		mirrorThicknesses()
 	}
 
 	// print title
-	pdf.SetFont("courier", "", 30)
-	pdf.Text(bnd.left, bnd.top+1.5*padding, hc.title)
+	pdf.SetFont(fontFamily, "", 30*scale)
+	pdf.Text(bnd.left, bnd.top+1.5*padding, encodeText(hc.title))
 
 	// print date
-	pdf.SetFont("courier", "", 14)
-	pdf.Text(bnd.right-dateRightOffset, bnd.top+padding, "DATE:"+hc.date)
+	pdf.SetFont(fontFamily, "", 14*scale)
+	pdf.Text(bnd.right-dateRightOffset, bnd.top+padding, encodeText("DATE:"+hc.date))
 
 	// print box
 	pdf.SetLineWidth(thinLW)
@@ -184,12 +190,12 @@ func printHeader(pdf *gofpdf.Fpdf, bnd bounds, hc *headerContent) (reducedBounds
 	}
 
 	// determine extra space available
-	pdf.SetFont("courier", "", 14)
-	charH := GetFontHeight(14)
+	pdf.SetFont(fontFamily, "", 14*scale)
+	charH := GetFontHeight(14 * scale)
 	charW := GetCourierFontWidthFromHeight(charH)
 	numChars := 0
 	for _, c := range conts {
-		numChars += len(c)
+		numChars += displayWidth(c)
 	}
 	usedWidth := charW * float64(numChars)
 
@@ -199,8 +205,8 @@ func printHeader(pdf *gofpdf.Fpdf, bnd bounds, hc *headerContent) (reducedBounds
 	freeWidthPerItem := (xTextAreaEnd - xTextAreaStart - usedWidth) / float64(len(conts))
 
 	for _, cont := range conts {
-		pdf.Text(xTextAreaStart+xTextIncr, bnd.top+totalHeaderHeight-boxTextMargin, cont)
-		xTextIncr += float64(len(cont))*charW + freeWidthPerItem
+		pdf.Text(xTextAreaStart+xTextIncr, bnd.top+totalHeaderHeight-boxTextMargin, encodeText(cont))
+		xTextIncr += float64(displayWidth(cont))*charW + freeWidthPerItem
 	}
 
 	return bounds{bnd.top + totalHeaderHeight + padding, bnd.left, bnd.bottom, bnd.right}
@@ -402,6 +408,24 @@ type pillar struct {
 // thicknesses of guitar strings from thick to thin
 var thicknesses = []float64{0.0472, 0.0314, 0.0236, 0.0157, 0.0079, 0.0039}
 
+var thicknessesMirrored = false
+
+// mirrorThicknesses reverses the thicknesses of the guitar strings, only
+// once no matter how many headers are printed (ex. within a songbook)
+func mirrorThicknesses() {
+	if thicknessesMirrored {
+		return
+	}
+	thicknessesRev := make([]float64, len(thicknesses))
+	j := len(thicknesses) - 1
+	for i := 0; i < len(thicknesses); i++ {
+		thicknessesRev[j] = thicknesses[i]
+		j--
+	}
+	thicknesses = thicknessesRev
+	thicknessesMirrored = true
+}
+
 var _ ssElement = pillar{}
 
 func (pil pillar) parseText(text string) (ssElement, error) {
@@ -5,7 +5,6 @@ import (
 	"io/ioutil"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -31,12 +30,21 @@ var (
 	SongsheetPlaybackTimeCmd = &cobra.Command{
 		Use:   "pt [filepath] [cursor-x] [cursor-y]",
 		Short: "return the playback time (mm:ss.cs) for the current position",
-		Args:  cobra.ExactArgs(3),
-		RunE:  playbackTimeCmd,
+		Long: `return the playback time (mm:ss.cs) for the current position, interpolated
+between the surrounding playback times following the tempo markings:
+  - rit. or accel. along the axis ramps the tempo until the end of the humps
+    of the sine, the new tempo is then kept until an "a tempo"
+  - trailing humps (....) and rests (an r along the sine lasting until the
+    next character along the sine) are held for whatever time remains
+Positions before the first or after the last playback time are extrapolated
+from the local tempo.`,
+		Args: cobra.ExactArgs(3),
+		RunE: playbackTimeCmd,
 	}
 )
 
 func init() {
+	registerTakeFlag(SongsheetPlaybackTimeCmd)
 	RootCmd.AddCommand(SongsheetPlaybackTimeCmd)
 }
 
@@ -49,9 +57,11 @@ func playbackTimeCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	lines := strings.Split(string(content), "\n")
-	origLenLines := len(lines)
-	lines = deleteComments(lines)
-	commentLines := origLenLines - len(lines)
+	take, err := songTake(lines)
+	if err != nil {
+		fmt.Printf("BAD-PLAYBACK-TIME")
+		return err
+	}
 
 	curX, err := strconv.Atoi(args[1])
 	if err != nil {
@@ -64,123 +74,48 @@ func playbackTimeCmd(cmd *cobra.Command, args []string) error {
 		fmt.Printf("BAD-PLAYBACK-TIME")
 		return err
 	}
-	curY -= commentLines
-
-	// get the list of all lines and sasses
-	lasses := getLasses(lines)
-
-	// determine the sas belonging to the cursor
-	curI := 0
-LOOP:
-	for i, s := range lasses {
-		switch {
-		case (curY - 1) == int(s.lineNo):
-			curI = i
-			break LOOP
-		case i == 0 && (curY-1) < int(s.lineNo):
-			curI = 0
-			break LOOP
-		case i > 0 && (curY-1) < int(s.lineNo):
-			curI = i - 1
-			break LOOP
-		case i == len(lasses)-1 && (curY-1) > int(s.lineNo):
-			curI = i
-			break LOOP
-		}
-	}
-
-	// convert the sasses into an array of characters
-	type charPos struct {
-		hasPT bool
-		pt    playbackTime
-	}
-	curPosInCharPoss := 0
-	charPoss := []charPos{}
-	for i, s := range lasses {
-		maxChars := int((s.sas.totalHumps() * charsToaHump) + 0.00001) // float rounding
-		for j := 0; j < maxChars; j++ {
-			cp := charPos{}
-			if s.sas.hasPlaybackTime && s.sas.ptCharPosition == j {
-				cp.hasPT = true
-				cp.pt = s.sas.pt
-			}
-			charPoss = append(charPoss, cp)
-
-			if i == curI && j == curX-1 {
-				curPosInCharPoss = len(charPoss) - 1
-			}
-		}
-	}
-
-	// shortcut if on a playback time
-	if charPoss[curPosInCharPoss].hasPT {
-		cp := charPoss[curPosInCharPoss]
-		fmt.Printf(cp.pt.str)
-		return nil
-	}
 
-	// get the first and last playback times surrounding the cursor
-	var ptFirst, ptLast playbackTime
-	ptFirstFound, ptLastFound := false, false
-	charsBetweenCurAndFirstPT, charsBetweenCurAndLastPT := 0, 0
-	for i := curPosInCharPoss; i >= 0; i-- {
-		cp := charPoss[i]
-		if cp.hasPT {
-			ptFirst = cp.pt
-			ptFirstFound = true
-			break
-		}
-		charsBetweenCurAndFirstPT++
-	}
-	for i := curPosInCharPoss; i < len(charPoss); i++ {
-		cp := charPoss[i]
-		if cp.hasPT {
-			ptLast = cp.pt
-			ptLastFound = true
-			break
-		}
-		charsBetweenCurAndLastPT++
-	}
-	if !ptFirstFound || !ptLastFound {
+	ptOut, found := getPlaybackTimeAtCursor(lines, take, curX, curY)
+	if !found {
 		fmt.Printf("BAD-PLAYBACK-TIME")
 		return nil
 	}
-	totalCharsBetweenFirstAndLastPT := charsBetweenCurAndFirstPT + charsBetweenCurAndLastPT
-
-	// determine the duration of time passing per hump
-	totalDur := ptLast.t.Sub(ptFirst.t)
-	durPerChar := float64(totalDur) / float64(totalCharsBetweenFirstAndLastPT)
-
-	// determine the playback time at the current hump
-	elapsedFromPtFirst := time.Duration(float64(charsBetweenCurAndFirstPT) * durPerChar)
-	ptOut := ptFirst.AddDur(elapsedFromPtFirst)
 	fmt.Printf(ptOut.str)
 	return nil
+}
 
-	/* OLD CODE for cursor movement (abandoned)
-	// potentially have to move more than one
-	// character if this calculation has taken to long
-	charMovements := 1
-	var finalSleep time.Duration
-	if diffTime <= durPerOneForthHump {
-		finalSleep = durPerOneForthHump - diffTime
-	} else {
-		charMovements += int(diffTime / durPerOneForthHump)
-		finalSleep = diffTime % durPerOneForthHump
+// getPlaybackTimeAtCursor returns the playback time at the cursor position
+// (starting from 1) within the lines of the songsheet file, positions before
+// the first or after the last playback time are extrapolated. The playback
+// times are those of the take.
+func getPlaybackTimeAtCursor(lines []string, take string, curX, curY int) (pt playbackTime, found bool) {
+	return newSongTimeline(lines, take).playbackTimeAtCursor(curX, curY)
+}
+
+// playbackTimeAtCursor returns the playback time at the cursor position
+// (starting from 1) within the original lines, as per getPlaybackTimeAtCursor
+func (st songTimeline) playbackTimeAtCursor(curX, curY int) (pt playbackTime, found bool) {
+	if len(st.charPoss) == 0 {
+		return pt, false
 	}
-	time.Sleep(finalSleep)
+	curY = strippedLineNo(st.origIndexes, curY)
+	curPosInCharPoss := st.lasses.cursorCharIndex(st.charPoss, curX, curY)
 
-	finalCurX, finalCurY, endReached := lasses.getNextPosition(
-		curX, middleSasIndex, charMovements)
+	// shortcut if on a playback time
+	if st.charPoss[curPosInCharPoss].hasPT {
+		return st.charPoss[curPosInCharPoss].pt, true
+	}
 
-	// set the position of the cursor
-	// -1 because so that first position is 1
-	if endReached {
-		output = fmt.Sprintf("END")
-		return output, nil
+	// determine the playback time at the current character
+	if !st.anchored || st.tl[curPosInCharPoss] < 0 {
+		return pt, false
 	}
 
-	output = fmt.Sprintf("%vgg%vlR \\<esc>", finalCurY, (finalCurX - 1))
-	return output, nil
-	*/
+	// keep to millisecond precision if the playback times are
+	for _, cp := range st.charPoss {
+		if cp.hasPT && cp.pt.millis {
+			pt.millis = true
+		}
+	}
+	return pt.AddDur(st.tl[curPosInCharPoss]), true
 }
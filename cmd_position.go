@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	SongsheetPositionCmd = &cobra.Command{
+		Use:   "pos [filepath] [mm:ss.cs]",
+		Short: "return the cursor position (line col) of the hump playing at the playback time",
+		Args:  cobra.ExactArgs(2),
+		RunE:  positionCmd,
+	}
+)
+
+func init() {
+	registerTakeFlag(SongsheetPositionCmd)
+	RootCmd.AddCommand(SongsheetPositionCmd)
+}
+
+func positionCmd(cmd *cobra.Command, args []string) error {
+
+	// get the relevant file
+	content, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		fmt.Printf("BAD-POSITION")
+		return err
+	}
+	lines := strings.Split(string(content), "\n")
+	take, err := songTake(lines)
+	if err != nil {
+		fmt.Printf("BAD-POSITION")
+		return err
+	}
+
+	target, _, found := getPlaybackTimeFromLine(args[1])
+	if !found {
+		fmt.Printf("BAD-POSITION")
+		return fmt.Errorf("could not parse playback time: %v", args[1])
+	}
+
+	lineNo, col, found := getCursorAtPlaybackTime(lines, take, target)
+	if !found {
+		fmt.Printf("BAD-POSITION")
+		return nil
+	}
+	fmt.Printf("%v %v", lineNo, col)
+	return nil
+}
+
+// getCursorAtPlaybackTime returns the cursor position (starting from 1) of the
+// hump character playing at the target time within the lines of the songsheet
+// file with the playback times of the take, the inverse of
+// getPlaybackTimeAtCursor
+func getCursorAtPlaybackTime(lines []string, take string, target playbackTime) (lineNo, col int, found bool) {
+	return newSongTimeline(lines, take).cursorAtPlaybackTime(target)
+}
+
+// cursorAtPlaybackTime returns the cursor position (starting from 1) within
+// the original lines, as per getCursorAtPlaybackTime
+func (st songTimeline) cursorAtPlaybackTime(target playbackTime) (lineNo, col int, found bool) {
+
+	// find the character playing at the target time
+	if !st.anchored {
+		return 0, 0, false
+	}
+	curPosInCharPoss, found := st.tl.charAt(target.elapsed())
+	if !found {
+		return 0, 0, false
+	}
+
+	// convert back into the position within the original file
+	cp := st.charPoss[curPosInCharPoss]
+	lineNo = int(st.lasses[cp.lassesIndex].lineNo) + lineNoOffsetToMiddleHump
+	return originalLineNo(st.origIndexes, lineNo), cp.col + 1, true
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	ServeCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "run a songsheet language server over stdio",
+		Long: `run a language server (json-rpc over stdio, as per the language server
+protocol) which keeps the open songsheets parsed in memory. The server
+publishes diagnostics from the element parsers, provides hover info for
+chords and melody numbers, and formats documents. Additionally the
+following songsheet requests are answered:
+
+  songsheet/playbackTime  {textDocument, position} -> {time} (as pt)
+  songsheet/position      {textDocument, time} -> position   (as pos)
+  songsheet/info          {textDocument} -> {isSongsheet, hasAudio, audioPath}
+                          (as is-ss and has-audio)`,
+		Args: cobra.NoArgs,
+		RunE: serveCmd,
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(ServeCmd)
+}
+
+func serveCmd(cmd *cobra.Command, args []string) error {
+	s := newLSPServer(os.Stdin, os.Stdout)
+	return s.serve()
+}
+
+type lspServer struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]*ssDocument // by uri
+	shutdown bool
+}
+
+func newLSPServer(in io.Reader, out io.Writer) *lspServer {
+	return &lspServer{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: make(map[string]*ssDocument),
+	}
+}
+
+// serve handles messages until the exit notification or the input closes
+func (s *lspServer) serve() error {
+	for {
+		msg, err := readRPCMessage(s.in)
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			var rerr *rpcError
+			if !errors.As(err, &rerr) {
+				return err
+			}
+			if err := s.respond(json.RawMessage("null"), nil, rerr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit before shutdown")
+			}
+			return nil
+		}
+
+		result, err := s.handleSafely(msg)
+		if len(msg.ID) == 0 {
+			// notifications are never responded to
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v: %v\n", msg.Method, err)
+			}
+			continue
+		}
+		var rerr *rpcError
+		if err != nil && !errors.As(err, &rerr) {
+			rerr = &rpcError{rpcInternalError, err.Error()}
+		}
+		if err := s.respond(msg.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *lspServer) respond(id json.RawMessage, result interface{}, rerr *rpcError) error {
+	resp := rpcResponse{JSONRPC: "2.0", ID: id}
+	if rerr != nil {
+		resp.Error = rerr
+		return writeRPCMessage(s.out, resp)
+	}
+	bz, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	resp.Result = bz
+	return writeRPCMessage(s.out, resp)
+}
+
+func (s *lspServer) notify(method string, params interface{}) error {
+	return writeRPCMessage(s.out, rpcNotification{"2.0", method, params})
+}
+
+// handleSafely handles the message, recovering from any parser panic so a
+// bad document can't take the server down
+func (s *lspServer) handleSafely(msg rpcMessage) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("panic handling %v: %v", msg.Method, r)
+		}
+	}()
+	return s.handle(msg)
+}
+
+func (s *lspServer) handle(msg rpcMessage) (result interface{}, err error) {
+	if s.shutdown && msg.Method != "exit" {
+		return nil, &rpcError{rpcInvalidRequest, "server is shut down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":           1, // full
+				"hoverProvider":              true,
+				"documentFormattingProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "songsheet"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params lspDidOpenParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		td := params.TextDocument
+		return nil, s.update(newSSDocument(td.URI, td.Version, td.Text))
+	case "textDocument/didChange":
+		var params lspDidChangeParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		td := params.TextDocument
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return nil, s.update(newSSDocument(td.URI, td.Version, text))
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+		}
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.notify("textDocument/publishDiagnostics", lspPublishDiagnosticsParams{
+			URI: params.TextDocument.URI, Diagnostics: []lspDiagnostic{},
+		})
+
+	case "textDocument/hover":
+		var params lspTextDocumentPositionParams
+		doc, err := s.document(msg, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		text, found := doc.hover(params.Position)
+		if !found {
+			return nil, nil
+		}
+		return lspHover{Contents: lspMarkupContent{"markdown", text}}, nil
+	case "textDocument/formatting":
+		var params struct {
+			TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+		}
+		doc, err := s.document(msg, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		formatted := doc.format()
+		if formatted == strings.Join(doc.lines, "\n") {
+			return []lspTextEdit{}, nil
+		}
+		return []lspTextEdit{{doc.fullRange(), formatted}}, nil
+
+	case "songsheet/playbackTime":
+		var params lspTextDocumentPositionParams
+		doc, err := s.document(msg, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		pos := params.Position
+		curX := utf16ToColumn(doc.line(pos.Line), pos.Character) + 1
+		pt, found := doc.timeline.playbackTimeAtCursor(curX, pos.Line+1)
+		if !found {
+			return nil, nil
+		}
+		return map[string]string{"time": pt.str}, nil
+	case "songsheet/position":
+		var params struct {
+			TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+			Time         string                    `json:"time"`
+		}
+		doc, err := s.document(msg, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		target, _, found := getPlaybackTimeFromLine(params.Time)
+		if !found {
+			return nil, &rpcError{rpcInvalidParams, fmt.Sprintf("could not parse playback time: %v", params.Time)}
+		}
+		lineNo, col, found := doc.timeline.cursorAtPlaybackTime(target)
+		if !found {
+			return nil, nil
+		}
+		return lspPosition{lineNo - 1, columnToUTF16(doc.line(lineNo-1), col-1)}, nil
+	case "songsheet/info":
+		var params struct {
+			TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+		}
+		doc, err := s.document(msg, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		hasAudio, audioPath, err := hasSongsheetAudio(doc.lines, doc.take)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"isSongsheet": strings.Contains(uriToPath(doc.uri), "songsheet"),
+			"hasAudio":    hasAudio,
+			"audioPath":   audioPath,
+		}, nil
+	}
+
+	if strings.HasPrefix(msg.Method, "$/") {
+		return nil, nil // optional notifications may be ignored
+	}
+	return nil, &rpcError{rpcMethodNotFound, fmt.Sprintf("method not found: %v", msg.Method)}
+}
+
+// update stores the newly parsed document and publishes its diagnostics
+func (s *lspServer) update(doc *ssDocument) error {
+	s.docs[doc.uri] = doc
+	return s.notify("textDocument/publishDiagnostics", lspPublishDiagnosticsParams{
+		URI:         doc.uri,
+		Version:     doc.version,
+		Diagnostics: doc.diagnostics,
+	})
+}
+
+// document unmarshals the params and returns the open document they refer to
+func (s *lspServer) document(msg rpcMessage, params interface{},
+	td *lspTextDocumentIdentifier) (*ssDocument, error) {
+
+	if err := unmarshalParams(msg, params); err != nil {
+		return nil, err
+	}
+	doc, found := s.docs[td.URI]
+	if !found {
+		return nil, &rpcError{rpcInvalidParams, fmt.Sprintf("document not open: %v", td.URI)}
+	}
+	return doc, nil
+}
+
+func unmarshalParams(msg rpcMessage, params interface{}) error {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		return &rpcError{rpcInvalidParams, err.Error()}
+	}
+	return nil
+}
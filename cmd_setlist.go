@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/spf13/cobra"
+)
+
+var (
+	SetlistCmd = &cobra.Command{
+		Use:   "setlist [songsheets...]",
+		Short: "report the timing and key flow of a setlist and print it",
+		Long: `report the duration of each song, the total set length, and the key,
+capo, and tuning changes between songs, then print a one-page setlist pdf.
+
+songsheets are provided in order as filepaths, qu-ids, or tag queries
+(see the book command). The key of each song is read from the directive:
+    // KEY=G`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: setlistCmd,
+	}
+
+	setlistTitleFlag string
+	setlistPDFFlag   bool
+)
+
+func init() {
+	SetlistCmd.PersistentFlags().StringVar(
+		&setlistTitleFlag, "title", "Setlist",
+		"title of the setlist")
+	SetlistCmd.PersistentFlags().BoolVar(
+		&setlistPDFFlag, "pdf", true,
+		"print the setlist pdf")
+	SetlistCmd.PersistentFlags().StringVar(
+		&fontFlag, "font", "",
+		"filepath to a monospaced UTF-8 (.ttf) font for non-latin text")
+	SetlistCmd.PersistentFlags().StringVar(
+		&fontBoldFlag, "font-bold", "",
+		"filepath to the bold variant of the UTF-8 font")
+	registerPageFlags(SetlistCmd)
+	RootCmd.AddCommand(SetlistCmd)
+}
+
+type setlistSong struct {
+	title    string
+	key      string
+	capo     string
+	tuning   string
+	bpm      string
+	dur      time.Duration
+	durFound bool
+}
+
+func setlistCmd(cmd *cobra.Command, args []string) error {
+
+	page, err := getPageLayout(cmd)
+	if err != nil {
+		return err
+	}
+
+	sources, err := resolveSongsheets(args)
+	if err != nil {
+		return err
+	}
+	var songs []setlistSong
+	for _, src := range sources {
+		take, err := contentTake(src.content)
+		if err != nil {
+			return fmt.Errorf("songsheet %v: %v", src.name, err)
+		}
+		ss, err := parseSongsheet(src.content, take)
+		if err != nil {
+			return fmt.Errorf("songsheet %v: %v", src.name, err)
+		}
+		key, _ := getDirective(strings.Split(string(src.content), "\n"), "KEY")
+		dur, durFound := songDuration(ss)
+		songs = append(songs, setlistSong{
+			title:    songTitle(ss.hc),
+			key:      key,
+			capo:     strings.TrimSpace(ss.hc.capo),
+			tuning:   headerTuning(ss.hc),
+			bpm:      strings.TrimSpace(ss.hc.bpm),
+			dur:      dur,
+			durFound: durFound,
+		})
+	}
+
+	// report
+	total, unknown := time.Duration(0), 0
+	fmt.Printf("%-3v %-30v %-8v %-8v %-4v %-4v %v\n",
+		"#", "TITLE", "START", "LENGTH", "KEY", "CAPO", "TUNING")
+	for i, s := range songs {
+		if i > 0 {
+			for _, change := range setlistChanges(songs[i-1], s) {
+				fmt.Printf("    -> %v\n", change)
+			}
+		}
+		length := "?"
+		if s.durFound {
+			length = formatSetDuration(s.dur)
+		} else {
+			unknown++
+		}
+		fmt.Printf("%-3v %-30v %-8v %-8v %-4v %-4v %v\n", i+1, s.title,
+			formatSetDuration(total), length, s.key, s.capo, s.tuning)
+		total += s.dur
+	}
+	fmt.Printf("\ntotal set length: %v", formatSetDuration(total))
+	if unknown > 0 {
+		fmt.Printf(" (plus %v songs of unknown length)", unknown)
+	}
+	fmt.Println()
+
+	if !setlistPDFFlag {
+		return nil
+	}
+	pdf := page.newPdf()
+	if err := initFonts(pdf); err != nil {
+		return err
+	}
+	printSetlist(pdf, page.bounds(), setlistTitleFlag, songs)
+	return pdf.OutputFileAndClose(fmt.Sprintf("setlist_%v.pdf", setlistTitleFlag))
+}
+
+// songDuration determines the length of the song from the last playback
+// time, with any remaining humps (or the whole song if there are no playback
+// times) extended at the header bpm
+func songDuration(ss songsheet) (dur time.Duration, found bool) {
+	lasses := getLasses(ss.lines, ss.take)
+	bpm, err := strconv.ParseFloat(strings.TrimSpace(ss.hc.bpm), 64)
+	hasBPM := err == nil && bpm > 0
+
+	pt, humpsAfter, ptFound := lasses.lastPlaybackTime()
+	switch {
+	case ptFound && hasBPM:
+		return pt.elapsed() + humpsDuration(humpsAfter, bpm), true
+	case ptFound:
+		return pt.elapsed(), true
+	case hasBPM:
+		return humpsDuration(lasses.totalHumps(), bpm), true
+	}
+	return 0, false
+}
+
+// humpsDuration returns the duration of the humps at the bpm (one beat per hump)
+func humpsDuration(humps, bpm float64) time.Duration {
+	return time.Duration(humps / bpm * float64(time.Minute))
+}
+
+// headerTuning returns all the tuning keys of the header as one string
+func headerTuning(hc headerContentFilled) string {
+	keys := []string{hc.tuningTopLeft, hc.tuningTopMid, hc.tuningTopRight,
+		hc.tuningBotLeft, hc.tuningBotMid, hc.tuningBotRight}
+	var tuning []string
+	for _, k := range keys {
+		if k = strings.TrimSpace(k); k != "" {
+			tuning = append(tuning, k)
+		}
+	}
+	return strings.Join(tuning, " ")
+}
+
+// setlistChanges describes what needs to be changed between two songs
+func setlistChanges(prev, next setlistSong) (changes []string) {
+	if prev.key != next.key && (prev.key != "" || next.key != "") {
+		changes = append(changes, fmt.Sprintf("key %v to %v",
+			orUnknown(prev.key), orUnknown(next.key)))
+	}
+	if prev.capo != next.capo {
+		changes = append(changes, fmt.Sprintf("capo %v to %v",
+			orUnknown(prev.capo), orUnknown(next.capo)))
+	}
+	if prev.tuning != next.tuning {
+		changes = append(changes, fmt.Sprintf("retune %v to %v",
+			orUnknown(prev.tuning), orUnknown(next.tuning)))
+	}
+	return changes
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "?"
+	}
+	return s
+}
+
+// formatSetDuration formats the duration as m:ss
+func formatSetDuration(d time.Duration) string {
+	secs := int(math.Round(d.Seconds()))
+	return fmt.Sprintf("%v:%02v", secs/60, secs%60)
+}
+
+// printSetlist prints every song of the setlist onto one page, large enough
+// to be read off the stage floor
+func printSetlist(pdf *gofpdf.Fpdf, bnd bounds, title string, songs []setlistSong) {
+
+	pdf.SetFont(fontFamily, "", 24)
+	titleH := GetFontHeight(24)
+	pdf.Text(bnd.left, bnd.top+titleH, encodeText(title))
+	top := bnd.top + titleH + padding
+
+	// each song gets a title line and a smaller line
+	// for the changes required before it
+	width := bnd.Width() - padding
+	height := bnd.bottom - padding - top
+	lineH := height / float64(len(songs))
+	titleFontPt := math.Min(48, GetFontPt(lineH/1.5/1.3))
+	noteFontPt := titleFontPt / 2.5
+
+	for i, s := range songs {
+		y := top + float64(i)*lineH
+		line := fmt.Sprintf("%v. %v", i+1, s.title)
+
+		// shrink long titles to fit across the page
+		fontPt := titleFontPt
+		fontW := GetCourierFontWidthFromHeight(GetFontHeight(fontPt))
+		if float64(displayWidth(line))*fontW > width {
+			fontPt = GetFontPt(GetCourierFontHeightFromWidth(width / float64(displayWidth(line))))
+		}
+		pdf.SetFont(fontFamily, "B", fontPt)
+		pdf.Text(bnd.left, y+GetFontHeight(titleFontPt), encodeText(line))
+
+		notes := []string{}
+		if s.key != "" {
+			notes = append(notes, "key "+s.key)
+		}
+		if s.capo != "" {
+			notes = append(notes, "capo "+s.capo)
+		}
+		if s.tuning != "" {
+			notes = append(notes, s.tuning)
+		}
+		if s.durFound {
+			notes = append(notes, formatSetDuration(s.dur))
+		}
+		pdf.SetFont(fontFamily, "", noteFontPt)
+		pdf.Text(bnd.left+padding, y+GetFontHeight(titleFontPt)+1.3*GetFontHeight(noteFontPt),
+			encodeText(strings.Join(notes, "  |  ")))
+	}
+}
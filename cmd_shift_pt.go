@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	ShiftPTCmd = &cobra.Command{
+		Use:   "shift-pt [filepath]",
+		Short: "shift and/or stretch every playback time to match a different recording",
+		Long: `shift and/or stretch every playback time (mm:ss.cs) of the songsheet, each
+new time being the old time multiplied by the --ratio plus the --offset.
+Alternatively the ratio and offset are fit from two --anchor flags each
+mapping an old playback time to its new playback time, for instance:
+
+  shift-pt songsheet_x --anchor 00:05.00=00:12.40 --anchor 02:50.00=03:01.10
+
+The character position of each playback time is kept.`,
+		Args: cobra.ExactArgs(1),
+		RunE: shiftPTCmd,
+	}
+
+	shiftPTOffsetFlag  string
+	shiftPTRatioFlag   float64
+	shiftPTAnchorsFlag []string
+)
+
+func init() {
+	ShiftPTCmd.PersistentFlags().StringVar(
+		&shiftPTOffsetFlag, "offset", "",
+		"offset to add to each playback time (mm:ss.cs or a duration like 1.5s, may be negative)")
+	ShiftPTCmd.PersistentFlags().Float64Var(
+		&shiftPTRatioFlag, "ratio", 1,
+		"ratio to stretch each playback time by")
+	ShiftPTCmd.PersistentFlags().StringSliceVar(
+		&shiftPTAnchorsFlag, "anchor", []string{},
+		"old=new playback times to fit the shift to (two required)")
+	registerTakeFlag(ShiftPTCmd)
+	RootCmd.AddCommand(ShiftPTCmd)
+}
+
+func shiftPTCmd(cmd *cobra.Command, args []string) error {
+	filepath := args[0]
+	content, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return err
+	}
+
+	ratio, offset := shiftPTRatioFlag, time.Duration(0)
+	switch {
+	case len(shiftPTAnchorsFlag) > 0:
+		if shiftPTOffsetFlag != "" || cmd.Flags().Changed("ratio") {
+			return errors.New("--anchor cannot be combined with --offset or --ratio")
+		}
+		ratio, offset, err = fitAnchors(shiftPTAnchorsFlag)
+		if err != nil {
+			return err
+		}
+	case shiftPTOffsetFlag != "":
+		offset, err = parseOffset(shiftPTOffsetFlag)
+		if err != nil {
+			return err
+		}
+	}
+	if ratio <= 0 {
+		return fmt.Errorf("ratio must be positive (have %v)", ratio)
+	}
+
+	origLines := strings.Split(string(content), "\n")
+	take, err := songTake(origLines)
+	if err != nil {
+		return err
+	}
+	out, err := shiftPlaybackTimes(origLines, take, ratio, offset)
+	if err != nil {
+		return err
+	}
+	return writeSongsheet(filepath, out)
+}
+
+// shiftPlaybackTimes returns the lines with every playback time multiplied
+// of the take by the ratio and then offset, keeping the position of each
+// playback time
+func shiftPlaybackTimes(origLines []string, take string, ratio float64, offset time.Duration) (
+	out []string, err error) {
+
+	lines, origIndexes := deleteCommentsMapped(origLines)
+	out = append([]string{}, origLines...)
+	for _, ls := range getLasses(lines, take) {
+		if !ls.sas.hasPlaybackTime() {
+			continue
+		}
+		pts := []sinePlaybackTime{}
+		for _, spt := range ls.sas.playbackTimes {
+			pt := spt.pt
+			newDur := time.Duration(float64(pt.elapsed())*ratio) + offset
+			if newDur < 0 {
+				return nil, fmt.Errorf("playback time %v would be shifted before the start", pt.str)
+			}
+			pts = append(pts, sinePlaybackTime{pt.AddDur(newDur - pt.elapsed()), spt.charPosition})
+		}
+
+		// rewrite the line keeping any comment
+		i := origIndexes[int(ls.lineNo)+ls.sas.ptLineOffset]
+		out[i] = keepComment(out[i], formatTakePlaybackTimesLine(take, pts))
+	}
+	return out, nil
+}
+
+// fitAnchors determines the ratio and offset mapping the two old playback
+// times onto the new playback times, each anchor is in the form old=new
+func fitAnchors(anchors []string) (ratio float64, offset time.Duration, err error) {
+	if len(anchors) != 2 {
+		return 0, 0, fmt.Errorf("two anchors are required (have %v)", len(anchors))
+	}
+	var olds, news [2]time.Duration
+	for i, anchor := range anchors {
+		splt := strings.SplitN(anchor, "=", 2)
+		if len(splt) != 2 {
+			return 0, 0, fmt.Errorf("anchor must be in the form old=new (have %v)", anchor)
+		}
+		oldPT, _, found := getPlaybackTimeFromLine(splt[0])
+		if !found {
+			return 0, 0, fmt.Errorf("could not parse playback time: %v", splt[0])
+		}
+		newPT, _, found := getPlaybackTimeFromLine(splt[1])
+		if !found {
+			return 0, 0, fmt.Errorf("could not parse playback time: %v", splt[1])
+		}
+		olds[i], news[i] = oldPT.elapsed(), newPT.elapsed()
+	}
+	if olds[0] == olds[1] {
+		return 0, 0, errors.New("the anchors must be at different playback times")
+	}
+	ratio = float64(news[1]-news[0]) / float64(olds[1]-olds[0])
+	offset = news[0] - time.Duration(float64(olds[0])*ratio)
+	return ratio, offset, nil
+}
+
+// parseOffset parses an offset either as a playback time or as a duration,
+// optionally negative
+func parseOffset(str string) (time.Duration, error) {
+	str = strings.TrimSpace(str)
+	sign := time.Duration(1)
+	unsigned := str
+	switch {
+	case strings.HasPrefix(str, "-"):
+		sign, unsigned = -1, str[1:]
+	case strings.HasPrefix(str, "+"):
+		unsigned = str[1:]
+	}
+	if pt, _, found := getPlaybackTimeFromLine(unsigned); found {
+		return sign * pt.elapsed(), nil
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse offset: %v", str)
+	}
+	return d, nil
+}
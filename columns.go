@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// songsheets are laid out by the display columns of a monospaced text
+// editor, not by bytes or runes. Accented characters written with
+// combining marks take up no extra column while east-asian wide characters
+// take up two.
+
+// runes which occupy two display columns within a monospaced editor
+var wideRunes = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1}, // hangul jamo
+		{Lo: 0x2e80, Hi: 0x303e, Stride: 1}, // cjk radicals, punctuation
+		{Lo: 0x3041, Hi: 0x33ff, Stride: 1}, // kana, cjk compatibility
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1}, // cjk extension a
+		{Lo: 0x4e00, Hi: 0x9fff, Stride: 1}, // cjk unified ideographs
+		{Lo: 0xa000, Hi: 0xa4cf, Stride: 1}, // yi
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1}, // hangul syllables
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1}, // cjk compatibility ideographs
+		{Lo: 0xfe30, Hi: 0xfe4f, Stride: 1}, // cjk compatibility forms
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1}, // fullwidth forms
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1}, //
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f300, Hi: 0x1f64f, Stride: 1}, // pictographs, emoticons
+		{Lo: 0x1f900, Hi: 0x1f9ff, Stride: 1}, // supplemental pictographs
+		{Lo: 0x20000, Hi: 0x2fffd, Stride: 1}, // cjk extensions b-f
+		{Lo: 0x30000, Hi: 0x3fffd, Stride: 1}, // cjk extension g
+	},
+}
+
+// runeWidth returns the number of display columns the rune occupies
+func runeWidth(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case unicode.Is(wideRunes, r):
+		return 2
+	}
+	return 1
+}
+
+// displayWidth returns the number of display columns used by the string
+func displayWidth(s string) (width int) {
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// splitColumns splits the string into its display columns, each column
+// holding the character which starts there along with any combining marks
+// which follow it. The second column of a wide character is left empty.
+func splitColumns(s string) (cols []string) {
+	for _, r := range s {
+		w := runeWidth(r)
+		if w == 0 && len(cols) > 0 {
+			// attach to the last character which was actually printed
+			i := len(cols) - 1
+			for ; i > 0 && cols[i] == ""; i-- {
+			}
+			cols[i] += string(r)
+			continue
+		}
+		cols = append(cols, string(r))
+		for ; w > 1; w-- {
+			cols = append(cols, "")
+		}
+	}
+	return cols
+}
+
+// columnRunes returns the leading rune of every display column of the string,
+// columns without a character of their own (the second half of a wide
+// character) are returned as a space.
+func columnRunes(s string) (runes []rune) {
+	for _, col := range splitColumns(s) {
+		if col == "" {
+			runes = append(runes, ' ')
+			continue
+		}
+		runes = append(runes, []rune(col)[0])
+	}
+	return runes
+}
+
+// sliceColumns returns the section of the string between the start
+// and end display columns, padding with spaces if the string is short
+func sliceColumns(s string, start, end int) string {
+	cols := splitColumns(s)
+	var sb strings.Builder
+	for i := start; i < end; i++ {
+		if i >= len(cols) {
+			sb.WriteString(" ")
+			continue
+		}
+		sb.WriteString(cols[i])
+	}
+	return sb.String()
+}
+
+// replaceColumns replaces the section of the string between the start and
+// end display columns, padding with spaces if the string is short
+func replaceColumns(s string, start, end int, repl string) string {
+	cols := splitColumns(s)
+	for len(cols) < start {
+		cols = append(cols, " ")
+	}
+	out := strings.Join(cols[:start], "") + repl
+	if end < len(cols) {
+		out += strings.Join(cols[end:], "")
+	}
+	return out
+}
@@ -7,8 +7,15 @@ const (
 )
 
 func deleteComments(lines []string) (out []string) {
+	out, _ = deleteCommentsMapped(lines)
+	return out
+}
+
+// deleteCommentsMapped deletes the comments the same as deleteComments while
+// also returning the original index of each of the remaining lines
+func deleteCommentsMapped(lines []string) (out []string, origIndexes []int) {
 LOOP:
-	for _, line := range lines {
+	for i, line := range lines {
 		switch {
 		// do not include this line
 		case strings.HasPrefix(line, commentPrefix):
@@ -21,10 +28,47 @@ LOOP:
 				panic("something wrong with strings library")
 			}
 			out = append(out, splt[0])
+			origIndexes = append(origIndexes, i)
 			continue LOOP
 		default:
 			out = append(out, line)
+			origIndexes = append(origIndexes, i)
 		}
 	}
-	return out
+	return out, origIndexes
+}
+
+// strippedLineNo converts a line number (starting from 1) of the original
+// lines into the line number of the lines with the comments deleted, comment
+// lines resolve to the line following them
+func strippedLineNo(origIndexes []int, lineNo int) int {
+	for i, orig := range origIndexes {
+		if orig >= lineNo-1 {
+			return i + 1
+		}
+	}
+	return len(origIndexes) + 1
+}
+
+// originalLineNo converts a line number (starting from 1) of the lines with
+// the comments deleted back into the line number of the original lines
+func originalLineNo(origIndexes []int, lineNo int) int {
+	if lineNo < 1 || lineNo > len(origIndexes) {
+		return lineNo
+	}
+	return origIndexes[lineNo-1] + 1
+}
+
+// keepComment returns the new line replacing the old line along with any
+// comment trailing the old line, kept at its column where there's room
+func keepComment(oldLine, newLine string) string {
+	splt := strings.SplitN(oldLine, commentPrefix, 2)
+	if len(splt) != 2 {
+		return newLine
+	}
+	pad := displayWidth(splt[0]) - displayWidth(newLine)
+	if pad < 1 {
+		pad = 1
+	}
+	return newLine + strings.Repeat(" ", pad) + commentPrefix + splt[1]
 }
@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestKeepComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldLine string
+		newLine string
+		want    string
+	}{
+		{
+			name:    "no comment",
+			oldLine: "00:01.00",
+			newLine: "00:02.00",
+			want:    "00:02.00",
+		},
+		{
+			name:    "comment kept at its column",
+			oldLine: "00:01.00      // confidence 0.87",
+			newLine: "00:02.00",
+			want:    "00:02.00      // confidence 0.87",
+		},
+		{
+			name:    "comment pushed along by a longer line",
+			oldLine: "00:01.00 // confidence 0.87",
+			newLine: "00:02.00    00:04.00",
+			want:    "00:02.00    00:04.00 // confidence 0.87",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := keepComment(tc.oldLine, tc.newLine); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// directives are comment lines holding the metadata of the songsheet
+// in the form:
+//   // KEY=VALUE
+// where KEY is made up of upper case letters, numbers, and dashes. The
+// directives in use are:
+//   // KEY=G               key of the song (ex. for the melody numbers)
+//   // BPM=120             bpm of the song (kept up to date by fill-bpm)
+//   // AUDIO-ID=12         linked audio, see takes.go for labelled takes
+//   // AUDIO-TAKE=live     the default take
+//   // SECTION=chorus      the section (ex. for audio-slice, cue export)
+//                          starting at the first sine below
+
+const directivePrefix = commentPrefix + " "
+
+const sectionDirectiveKey = "SECTION"
+
+// parseDirective returns the key and value of the directive line
+func parseDirective(line string) (key, value string, ok bool) {
+	if !strings.HasPrefix(line, directivePrefix) {
+		return "", "", false
+	}
+	splt := strings.SplitN(strings.TrimPrefix(line, directivePrefix), "=", 2)
+	if len(splt) != 2 || !isDirectiveKey(splt[0]) {
+		return "", "", false
+	}
+	return splt[0], strings.TrimSpace(splt[1]), true
+}
+
+func isDirectiveKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func formatDirective(key, value string) string {
+	return directivePrefix + key + "=" + value
+}
+
+// findDirective returns the line index and value of the first directive
+// with the key
+func findDirective(lines []string, key string) (index int, value string, found bool) {
+	for i, line := range lines {
+		if k, v, ok := parseDirective(line); ok && k == key {
+			return i, v, true
+		}
+	}
+	return 0, "", false
+}
+
+// getDirective returns the value of the first "// KEY=VALUE" comment line
+func getDirective(lines []string, key string) (value string, found bool) {
+	_, value, found = findDirective(lines, key)
+	return value, found
+}
+
+// setDirective returns the lines with the directive updated in place (with
+// any duplicates removed) or, if it doesn't yet exist, added after the
+// directives at the top of the lines
+func setDirective(lines []string, key, value string) (out []string) {
+	newLine := formatDirective(key, value)
+	if index, _, found := findDirective(lines, key); found {
+		out = removeDirective(lines, key)
+		return insertLine(out, index, newLine)
+	}
+
+	index := 0
+	for index < len(lines) {
+		if _, _, ok := parseDirective(lines[index]); !ok {
+			break
+		}
+		index++
+	}
+	return insertLine(lines, index, newLine)
+}
+
+// removeDirective returns the lines without any of the directives with the key
+func removeDirective(lines []string, key string) (out []string) {
+	for _, line := range lines {
+		if k, _, ok := parseDirective(line); ok && k == key {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+func insertLine(lines []string, index int, line string) (out []string) {
+	out = append(out, lines[:index]...)
+	out = append(out, line)
+	return append(out, lines[index:]...)
+}
+
+// writeSongsheet atomically writes the lines to the songsheet file so that
+// a failure part way through never leaves the file changed
+func writeSongsheet(path string, lines []string) error {
+	return writeFileAtomic(path, []byte(strings.Join(lines, "\n")))
+}
+
+// writeFileAtomic writes to a temporary file alongside the file and then
+// renames it over the file, keeping the permissions of the file
+func writeFileAtomic(path string, content []byte) error {
+	mode := os.FileMode(0666)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	fail := func(err error) error {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// chordProChord is a chord (or other annotation) along the axis of a sine
+// to be placed inline within the lyrics under the sine
+type chordProChord struct {
+	col, end   int // display columns
+	text       string
+	annotation bool // not a chord, exported as [*text]
+}
+
+// chordProEnvironments are the sections with their own ChordPro environment
+// (ex. {start_of_chorus}), the other sections are exported as comments
+var chordProEnvironments = []string{"verse", "chorus", "bridge", "tab", "grid"}
+
+// exportChordPro exports the songsheet as a ChordPro chord sheet, the chords
+// along each sine are placed inline within the first lyric line under the
+// sine, the chord charts become {define} directives, and the sections (as
+// per the SECTION directive) become environments or comments
+func exportChordPro(src songsheetSource, ss songsheet) (out []byte, err error) {
+	var buf bytes.Buffer
+	directive := func(name, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			fmt.Fprintf(&buf, "{%v: %v}\n", name, value)
+		}
+	}
+	directive("title", ss.hc.title)
+	directive("subtitle", ss.hc.titleLine2)
+	directive("key", exportKeyString(src))
+	directive("capo", ss.hc.capo)
+	directive("tempo", ss.hc.bpm)
+	top, bottom := strings.TrimSpace(ss.hc.timesigTop), strings.TrimSpace(ss.hc.timesigBottom)
+	if top != "" && bottom != "" {
+		directive("time", top+"/"+bottom)
+	}
+
+	// the chords of the sine above waiting for a lyric line
+	var chords []chordProChord
+	flush := func() {
+		if len(chords) > 0 {
+			fmt.Fprintln(&buf, chordProLine("", chords))
+		}
+		chords = nil
+	}
+
+	// each section runs until the next section, or the end of the song
+	type section struct {
+		lineIndex int
+		label     string
+	}
+	sections := []section{}
+	for i, line := range strings.Split(string(src.content), "\n") {
+		if key, label, ok := parseDirective(line); ok && key == sectionDirectiveKey {
+			sections = append(sections, section{i, label})
+		}
+	}
+	openEnv := ""
+	closeEnv := func() {
+		if openEnv != "" {
+			fmt.Fprintf(&buf, "{end_of_%v}\n", openEnv)
+		}
+		openEnv = ""
+	}
+
+	// blank lines are held back so that sections end before them
+	blanks := 0
+	writeBlanks := func() {
+		buf.WriteString(strings.Repeat("\n", blanks))
+		blanks = 0
+	}
+
+	for elI, el := range ss.elems {
+		for len(sections) > 0 && sections[0].lineIndex < ss.elemLines[elI] {
+			flush()
+			closeEnv()
+			writeBlanks()
+			label := sections[0].label
+			sections = sections[1:]
+			openEnv = chordProEnvironment(label)
+			switch {
+			case openEnv == "":
+				directive("comment", label)
+			case openEnv == strings.ToLower(label):
+				fmt.Fprintf(&buf, "{start_of_%v}\n", openEnv)
+			default:
+				fmt.Fprintf(&buf, "{start_of_%v: label=\"%v\"}\n", openEnv,
+					strings.Replace(label, `"`, "'", -1))
+			}
+		}
+
+		if _, ok := el.(spacer); !ok {
+			writeBlanks()
+		}
+		switch el := el.(type) {
+		case sine:
+			flush()
+			chords = sineChordProChords(el)
+		case lyrics:
+			fmt.Fprintln(&buf, chordProLine(el.lyrics, chords))
+			chords = nil
+		case spacer:
+			flush()
+			blanks++
+		case chordChart:
+			flush()
+			for _, c := range el.chords {
+				fmt.Fprintln(&buf, chordProDefine(c))
+			}
+		}
+	}
+	flush()
+	closeEnv()
+	return buf.Bytes(), nil
+}
+
+// chordProEnvironment returns the environment of the section by the first
+// word of its label (ex. verse for "Verse 2"), empty if there's none
+func chordProEnvironment(label string) string {
+	words := strings.Fields(strings.ToLower(label))
+	if len(words) == 0 {
+		return ""
+	}
+	for _, env := range chordProEnvironments {
+		if words[0] == env {
+			return env
+		}
+	}
+	return ""
+}
+
+// sineChordProChords returns the chords along the axis of the sine by
+// display column. Annotations which run together (ex. C # m) are joined.
+func sineChordProChords(s sine) (chords []chordProChord) {
+	for _, sa := range s.alongAxis {
+		if sa.isMelody {
+			continue
+		}
+		col := int(math.Round(sa.position * charsToaHump))
+		text := sa.chordName()
+		if sa.slide {
+			text += "/"
+		}
+		if n := len(chords); n > 0 && chords[n-1].end == col {
+			chords[n-1].text += text
+			chords[n-1].end += displayWidth(text)
+			continue
+		}
+		chords = append(chords, chordProChord{col, col + displayWidth(text), text, !sa.isChord()})
+	}
+	return chords
+}
+
+// chordProLine places the chords inline within the lyric at their columns,
+// chords beyond the end of the lyric are spaced out by their columns
+func chordProLine(lyric string, chords []chordProChord) string {
+	cols := splitColumns(strings.TrimRight(lyric, " "))
+	var sb strings.Builder
+	ci := 0
+	for col := 0; col < len(cols) || ci < len(chords); col++ {
+		for ; ci < len(chords) && chords[ci].col <= col; ci++ {
+			if chords[ci].annotation {
+				fmt.Fprintf(&sb, "[*%v]", chords[ci].text)
+				continue
+			}
+			fmt.Fprintf(&sb, "[%v]", chords[ci].text)
+		}
+		switch {
+		case col < len(cols):
+			sb.WriteString(cols[col])
+		case ci < len(chords):
+			sb.WriteString(" ")
+		}
+	}
+	return sb.String()
+}
+
+// chordProDefine defines the chord of the chord chart by its frets (from
+// thick to thin strings), positions which aren't frets are unplayed
+func chordProDefine(c Chord) string {
+	frets := []string{}
+	for _, p := range c.positions {
+		p = strings.TrimSpace(p)
+		if p == "o" || p == "O" {
+			p = "0"
+		}
+		if _, err := strconv.Atoi(p); err != nil {
+			p = "x"
+		}
+		frets = append(frets, p)
+	}
+	return fmt.Sprintf("{define: %v base-fret 1 frets %v}", c.name, strings.Join(frets, " "))
+}
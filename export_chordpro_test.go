@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportChordProSections(t *testing.T) {
+	content := testHeader + `// SECTION=Verse 1
+G       C
+_   _   _
+ \_/ \_/ \_/
+
+hello there world
+
+// SECTION=chorus
+D
+_
+ \_/
+
+la la
+// SECTION=outro
+G
+_
+ \_/
+`
+	ss, err := parseSongsheet([]byte(content), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := exportChordPro(songsheetSource{content: []byte(content)}, ss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{title: Test Song}
+{tempo: 60}
+{time: 4/4}
+{start_of_verse: label="Verse 1"}
+[G]hello th[C]ere world
+{end_of_verse}
+
+{start_of_chorus}
+[D]la la
+{end_of_chorus}
+{comment: outro}
+[G]
+`
+	if string(out) != want {
+		t.Errorf("got:\n%v\nwant:\n%v", string(out), want)
+	}
+
+	// the sections survive being imported again
+	lines, _ := importChordPro(string(out), "x", time.Now())
+	imported := strings.Join(lines, "\n")
+	for _, section := range []string{"// SECTION=Verse 1", "// SECTION=chorus"} {
+		if !strings.Contains(imported, section) {
+			t.Errorf("missing %q from the import:\n%v", section, imported)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// cueFramesPerSecond is the resolution of cue sheet times (mm:ss:ff)
+const cueFramesPerSecond = 75
+
+// cueTrack is a track of the cue sheet, an index into the audio file
+type cueTrack struct {
+	title     string
+	audioPath string
+	fileType  string
+	start     time.Duration
+}
+
+// exportCue exports the sections of the songsheet (or the whole song if
+// there are no sections) as tracks of a cue sheet of its linked audio
+func exportCue(src songsheetSource, ss songsheet) (out []byte, err error) {
+	return exportCueSongs([]songsheetSource{src}, []songsheet{ss})
+}
+
+// exportCueSongs exports the songsheets, for instance the songs of a live
+// recording, as a single cue sheet. With several songs each section track
+// is titled with the song title as well.
+func exportCueSongs(srcs []songsheetSource, sss []songsheet) (out []byte, err error) {
+	tracks := []cueTrack{}
+	for i, src := range srcs {
+		songTracks, err := songCueTracks(src, sss[i], len(srcs) > 1)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", src.name, err)
+		}
+		tracks = append(tracks, songTracks...)
+	}
+
+	var buf bytes.Buffer
+	if len(srcs) == 1 {
+		fmt.Fprintf(&buf, "TITLE %v\n", cueQuote(sss[0].hc.title))
+	}
+	audioPath := ""
+	for i, tr := range tracks {
+		if tr.audioPath != audioPath {
+			audioPath = tr.audioPath
+			fmt.Fprintf(&buf, "FILE %v %v\n", cueQuote(audioPath), tr.fileType)
+		}
+		fmt.Fprintf(&buf, "  TRACK %02d AUDIO\n", i+1)
+		fmt.Fprintf(&buf, "    TITLE %v\n", cueQuote(tr.title))
+		fmt.Fprintf(&buf, "    INDEX 01 %v\n", cueTime(tr.start))
+	}
+	return buf.Bytes(), nil
+}
+
+// songCueTracks returns a track for each section of the song
+func songCueTracks(src songsheetSource, ss songsheet, titled bool) (tracks []cueTrack, err error) {
+	origLines := strings.Split(string(src.content), "\n")
+
+	has, audioPath, err := hasSongsheetAudio(origLines, ss.take)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, fmt.Errorf("no linked audio for the cue sheet")
+	}
+	ai, err := readAudioInfo(audioPath)
+	if err != nil {
+		return nil, err
+	}
+	fileType := "WAVE" // also used for flac
+	if ai.format == "mp3" {
+		fileType = "MP3"
+	}
+
+	clips, err := songClips(origLines, ss.take, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(clips) == 0 {
+		// the whole song from its first sine
+		clips, err = songClips(origLines, ss.take, []int{1})
+		if err != nil {
+			return nil, err
+		}
+		clips[0].label = ss.hc.title
+		titled = false
+	}
+
+	for _, clip := range clips {
+		title := clip.label
+		if titled {
+			title = ss.hc.title + " - " + clip.label
+		}
+		tracks = append(tracks, cueTrack{title, audioPath, fileType, clip.start})
+	}
+	return tracks, nil
+}
+
+// cueTime formats the duration as mm:ss:ff (75 frames per second)
+func cueTime(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	frames := int(math.Round(d.Seconds() * cueFramesPerSecond))
+	return fmt.Sprintf("%02d:%02d:%02d", frames/cueFramesPerSecond/60,
+		frames/cueFramesPerSecond%60, frames%cueFramesPerSecond)
+}
+
+// cueQuote quotes the string, cue sheets have no escape for quotes
+func cueQuote(s string) string {
+	return `"` + strings.Replace(s, `"`, "'", -1) + `"`
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// timedLyric is a lyric line timed from the sine it sits under
+type timedLyric struct {
+	text       string
+	start, end time.Duration
+	words      []timedWord
+}
+
+type timedWord struct {
+	text  string
+	start time.Duration
+}
+
+// timedLyrics times every lyric line (and word) by the playback time of the
+// character of the sine above it at the same column, as per the pt command.
+// Each line lasts until the next line, or until the end of its sine. Only the
+// first of the lyric lines stacked under a sine (ex. the first verse) is
+// timed, the same as import-lrc, the others are returned as skipped.
+func timedLyrics(ss songsheet) (tls []timedLyric, skipped []skippedLyric, err error) {
+	lasses, elemIndexes := elemLasses(ss.elems)
+	charPoss := lasses.charPositions()
+	tl, anchored := newCharTimeline(lasses.charWeights(), charPoss, headerBPM(ss.hc))
+	if !anchored {
+		return nil, nil, errors.New("no playback times to time the lyrics with")
+	}
+
+	// the first and end character index of each sine
+	firstChars := make([]int, len(lasses))
+	endChars := make([]int, len(lasses))
+	for i := len(charPoss) - 1; i >= 0; i-- {
+		firstChars[charPoss[i].lassesIndex] = i
+	}
+	for i, cp := range charPoss {
+		endChars[cp.lassesIndex] = i + 1
+	}
+
+	lasI := -1      // the sine above the current element
+	timedLasI := -1 // the sine above the last timed lyric line
+	for elI, el := range ss.elems {
+		if lasI+1 < len(lasses) && elemIndexes[lasI+1] == elI {
+			lasI++
+			continue
+		}
+		lyr, ok := el.(lyrics)
+		if !ok || lasI < 0 || strings.TrimSpace(lyr.lyrics) == "" {
+			continue
+		}
+		if lasI == timedLasI {
+			skipped = append(skipped, skippedLyric{strings.TrimSpace(lyr.lyrics), ss.elemLines[elI] + 1})
+			continue
+		}
+		timedLasI = lasI
+		at := func(col int) time.Duration {
+			ci := firstChars[lasI] + col
+			if ci >= endChars[lasI] {
+				ci = endChars[lasI] - 1 // beyond the end of the sine
+			}
+			return tl[ci]
+		}
+
+		tly := timedLyric{text: strings.TrimSpace(lyr.lyrics), end: tl[endChars[lasI]]}
+		inWord := false
+		for col, ch := range splitColumns(lyr.lyrics) {
+			switch {
+			case ch == "":
+				// second half of a wide character
+			case strings.TrimSpace(ch) == "":
+				inWord = false
+			case !inWord:
+				tly.words = append(tly.words, timedWord{ch, at(col)})
+				inWord = true
+			default:
+				tly.words[len(tly.words)-1].text += ch
+			}
+		}
+		tly.start = tly.words[0].start
+
+		// the previous line ends no later than this one starts
+		if len(tls) > 0 {
+			prev := &tls[len(tls)-1]
+			if tly.start < prev.end {
+				prev.end = tly.start
+			}
+		}
+		tls = append(tls, tly)
+	}
+	if len(tls) == 0 {
+		return nil, nil, errors.New("no lyrics under the sines to export")
+	}
+	return tls, skipped, nil
+}
+
+// skippedLyric is a lyric line stacked under a sine which wasn't timed
+type skippedLyric struct {
+	text   string
+	lineNo int // within the songsheet file, starting from 1
+}
+
+// exportTimedLyrics times the lyrics for export as per timedLyrics, warning
+// of the lyric lines which are skipped
+func exportTimedLyrics(ss songsheet) (tls []timedLyric, err error) {
+	tls, skipped, err := timedLyrics(ss)
+	if err != nil {
+		return nil, err
+	}
+	for _, sl := range skipped {
+		fmt.Fprintf(os.Stderr, "warning: skipped %q (line %v), only the first of the "+
+			"lyric lines stacked under a sine is timed\n", sl.text, sl.lineNo)
+	}
+	return tls, nil
+}
+
+// exportLRC exports the lyrics as lrc, with each word timed as well for
+// enhanced lrc
+func exportLRC(src songsheetSource, ss songsheet) (out []byte, err error) {
+	tls, err := exportTimedLyrics(ss)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "[ti:%v]\n", songTitle(ss.hc))
+	for _, tly := range tls {
+		if !exportWordsFlag {
+			fmt.Fprintf(&buf, "[%v]%v\n", lrcTime(tly.start), tly.text)
+			continue
+		}
+		fmt.Fprintf(&buf, "[%v]", lrcTime(tly.start))
+		for i, w := range tly.words {
+			if i > 0 {
+				buf.WriteString(" ")
+			}
+			fmt.Fprintf(&buf, "<%v>%v", lrcTime(w.start), w.text)
+		}
+		fmt.Fprintf(&buf, " <%v>\n", lrcTime(tly.end))
+	}
+	return buf.Bytes(), nil
+}
+
+// exportSRT exports the lyrics as srt subtitles
+func exportSRT(src songsheetSource, ss songsheet) (out []byte, err error) {
+	tls, err := exportTimedLyrics(ss)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for i, tly := range tls {
+		fmt.Fprintf(&buf, "%v\n%v --> %v\n%v\n\n", i+1,
+			subtitleTime(tly.start, ","), subtitleTime(tly.end, ","), tly.text)
+	}
+	return buf.Bytes(), nil
+}
+
+// exportVTT exports the lyrics as webvtt captions
+func exportVTT(src songsheetSource, ss songsheet) (out []byte, err error) {
+	tls, err := exportTimedLyrics(ss)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString("WEBVTT\n\n")
+	for _, tly := range tls {
+		fmt.Fprintf(&buf, "%v --> %v\n%v\n\n",
+			subtitleTime(tly.start, "."), subtitleTime(tly.end, "."), tly.text)
+	}
+	return buf.Bytes(), nil
+}
+
+// lrcTime formats the duration as mm:ss.cs
+func lrcTime(d time.Duration) string {
+	if d < 0 {
+		d = 0 // before the recording
+	}
+	cs := d.Round(10*time.Millisecond) / (10 * time.Millisecond)
+	return fmt.Sprintf("%02d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
+
+// subtitleTime formats the duration as hh:mm:ss,mmm (srt) or hh:mm:ss.mmm (vtt)
+func subtitleTime(d time.Duration, sep string) string {
+	if d < 0 {
+		d = 0 // before the recording
+	}
+	ms := d.Round(time.Millisecond) / time.Millisecond
+	return fmt.Sprintf("%02d:%02d:%02d%v%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
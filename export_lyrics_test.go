@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+const testHeader = `Test Song           DATE:2021-01-01
+                    4 60       E A D
+                    4          G B E
+
+`
+
+func TestTimedLyrics(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantTexts   []string
+		wantStarts  []time.Duration
+		wantSkipped []skippedLyric
+	}{
+		{
+			name: "a line under each sine",
+			body: `C
+_   _
+ \_/ \_/
+
+00:01.00
+one two
+G
+_   _
+ \_/ \_/
+
+00:05.00
+    three`,
+			wantTexts:  []string{"one two", "three"},
+			wantStarts: []time.Duration{time.Second, 7 * time.Second},
+		},
+		{
+			name: "stacked lines under a sine",
+			body: `C
+_   _
+ \_/ \_/
+
+00:01.00
+verse one
+verse two
+G
+_   _
+ \_/ \_/
+
+00:05.00
+chorus`,
+			wantTexts:   []string{"verse one", "chorus"},
+			wantStarts:  []time.Duration{time.Second, 5 * time.Second},
+			wantSkipped: []skippedLyric{{"verse two", 11}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ss, err := parseSongsheet([]byte(testHeader+tc.body), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tls, skipped, err := timedLyrics(ss)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(skipped) != len(tc.wantSkipped) {
+				t.Fatalf("got skipped %v, want %v", skipped, tc.wantSkipped)
+			}
+			for i := range skipped {
+				if skipped[i] != tc.wantSkipped[i] {
+					t.Errorf("got skipped %v, want %v", skipped[i], tc.wantSkipped[i])
+				}
+			}
+			if len(tls) != len(tc.wantTexts) {
+				t.Fatalf("got %v lines, want %v", len(tls), len(tc.wantTexts))
+			}
+			for i, tly := range tls {
+				if tly.text != tc.wantTexts[i] || tly.start != tc.wantStarts[i] {
+					t.Errorf("line %v: got %q at %v, want %q at %v",
+						i, tly.text, tly.start, tc.wantTexts[i], tc.wantStarts[i])
+				}
+				if tly.end <= tly.start {
+					t.Errorf("line %v: ends (%v) no later than it starts (%v)", i, tly.end, tly.start)
+				}
+			}
+		})
+	}
+}
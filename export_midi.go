@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func humpsToTicks(humps float64) uint32 {
+	return uint32(math.Round(humps * midiTicksPerBeat))
+}
+
+// exportMIDI creates a midi file with a melody track from the melody numbers
+// and a chord track from the chords along the sine axis, one beat per hump
+func exportMIDI(src songsheetSource, ss songsheet) (out []byte, err error) {
+
+	key, keyFound, err := exportKey(src)
+	if err != nil {
+		return nil, err
+	}
+
+	sines := songSines(ss.elems)
+	if len(sines) == 0 {
+		return nil, errors.New("no sines found within the songsheet")
+	}
+	songEnd := sines[len(sines)-1].end()
+
+	conductor, melodyTrack, chordTrack := &midiTrack{}, &midiTrack{}, &midiTrack{}
+	conductor.name(songTitle(ss.hc))
+	melodyTrack.name("melody")
+	chordTrack.name("chords")
+	melodyTrack.program(0, 73) // flute
+	chordTrack.program(1, 24)  // nylon guitar
+
+	// time signature and tempo
+	top, errTop := strconv.Atoi(strings.TrimSpace(ss.hc.timesigTop))
+	bottom, errBottom := strconv.Atoi(strings.TrimSpace(ss.hc.timesigBottom))
+	if errTop == nil && errBottom == nil && top > 0 && bottom > 0 && bottom&(bottom-1) == 0 {
+		conductor.timeSignature(0, top, bottom)
+	}
+	addMIDITempos(conductor, ss.hc, songPlaybackMarkers(sines))
+
+	// gather the melody and chords of each sine
+	type timedMelody struct {
+		humps float64
+		m     melody
+	}
+	type timedChord struct {
+		humps float64
+		notes []int
+	}
+	var chords []timedChord
+	var lastSine timedSine
+	melodiesBySine := make(map[int][]timedMelody)
+	sineIndex := -1
+	for _, el := range ss.elems {
+		switch e := el.(type) {
+		case sine:
+			sineIndex++
+			lastSine = sines[sineIndex]
+			for _, aa := range e.alongAxis {
+				pos := lastSine.start + aa.position
+				switch {
+				case aa.isMelody:
+					melodiesBySine[sineIndex] = append(melodiesBySine[sineIndex],
+						timedMelody{pos, aa.mel})
+				case aa.isChord():
+					notes, ok := chordNotes(aa.chordName())
+					if ok {
+						chords = append(chords, timedChord{pos, notes})
+					}
+				}
+			}
+		case melodies:
+			if sineIndex < 0 {
+				continue
+			}
+			for i, m := range e {
+				if m.num == ' ' {
+					continue
+				}
+				pos := lastSine.start + float64(i)/charsToaHump
+				melodiesBySine[sineIndex] = append(melodiesBySine[sineIndex],
+					timedMelody{pos, m})
+			}
+		}
+	}
+
+	// each melody note lasts until the next note or the end of its sine
+	for i, ts := range sines {
+		mels := melodiesBySine[i]
+		sort.SliceStable(mels, func(a, b int) bool { return mels[a].humps < mels[b].humps })
+		if len(mels) > 0 && !keyFound {
+			return nil, errors.New("a key is required for the melody " +
+				"(provide --key or a // KEY= directive)")
+		}
+		for j, tm := range mels {
+			end := ts.end()
+			if j+1 < len(mels) && mels[j+1].humps < end {
+				end = mels[j+1].humps
+			}
+			note, ok := tm.m.midiNote(key)
+			if !ok || end <= tm.humps {
+				continue
+			}
+			melodyTrack.note(0, humpsToTicks(tm.humps), humpsToTicks(end), byte(note), 90)
+		}
+	}
+
+	// each chord lasts until the next chord or the end of the song
+	for i, tc := range chords {
+		end := songEnd
+		if i+1 < len(chords) {
+			end = chords[i+1].humps
+		}
+		if end <= tc.humps {
+			continue
+		}
+		for _, note := range tc.notes {
+			chordTrack.note(1, humpsToTicks(tc.humps), humpsToTicks(end), byte(note), 70)
+		}
+	}
+
+	return writeMIDI([]*midiTrack{conductor, melodyTrack, chordTrack}), nil
+}
+
+// addMIDITempos sets the tempo between each pair of playback times, falling
+// back on the header bpm when there are not enough playback times
+func addMIDITempos(t *midiTrack, hc headerContentFilled, markers []playbackMarker) {
+	tempos := 0
+	for i := 0; i+1 < len(markers); i++ {
+		humps := markers[i+1].humps - markers[i].humps
+		minutes := markers[i+1].pt.t.Sub(markers[i].pt.t).Minutes()
+		if humps <= 0 || minutes <= 0 {
+			continue
+		}
+		tick := humpsToTicks(markers[i].humps)
+		if tempos == 0 {
+			tick = 0 // the first tempo is used from the start of the song
+		}
+		t.tempo(tick, humps/minutes)
+		tempos++
+	}
+	if tempos > 0 {
+		return
+	}
+
+	t.tempo(0, headerBPM(hc))
+}
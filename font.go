@@ -1,10 +1,43 @@
 package main
 
+import "github.com/jung-kurt/gofpdf"
+
 const ( // empirically determined
 	ptToHeight    = 100  //72
 	widthToHeight = 0.82 //
 )
 
+var (
+	// font family used for all songsheet text, the core courier font
+	// unless a UTF-8 font is provided through the flags
+	fontFamily = "courier"
+
+	// translates the UTF-8 text of the songsheet into the encoding
+	// expected by the current font
+	encodeText = func(s string) string { return s }
+
+	fontFlag     string
+	fontBoldFlag string
+)
+
+// initFonts registers the UTF-8 font if one has been provided, otherwise the
+// text is translated for the core fonts which can only draw latin characters
+func initFonts(pdf *gofpdf.Fpdf) error {
+	if fontFlag == "" {
+		encodeText = pdf.UnicodeTranslatorFromDescriptor("") // cp1252
+		return nil
+	}
+	boldFont := fontBoldFlag
+	if boldFont == "" {
+		boldFont = fontFlag
+	}
+	pdf.AddUTF8Font("songsheet", "", fontFlag)
+	pdf.AddUTF8Font("songsheet", "B", boldFont)
+	fontFamily = "songsheet"
+	encodeText = func(s string) string { return s }
+	return pdf.Error()
+}
+
 func GetFontPt(heightInches float64) float64 {
 	return heightInches * ptToHeight
 }
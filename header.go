@@ -42,56 +42,89 @@ func parseHeader(lines []string) (reduced []string, hc headerContentFilled, err
 		hc.titleLine2 = strings.TrimRight(splt2[0], " ")
 	}
 
-	datePos := len(splt[0])
-	hc.timesigTop = string(lines[1][datePos])
-	hc.timesigBottom = string(lines[2][datePos])
-	hc.bpm = string(lines[1][datePos+2 : datePos+5])
-	hc.capo = string(lines[2][datePos+8 : datePos+10])
+	// the header fields sit in the display columns under "DATE:"
+	datePos := displayWidth(splt[0])
+	hc.timesigTop = sliceColumns(lines[1], datePos, datePos+1)
+	hc.timesigBottom = sliceColumns(lines[2], datePos, datePos+1)
+	hc.bpm = sliceColumns(lines[1], datePos+2, datePos+5)
+	hc.capo = sliceColumns(lines[2], datePos+8, datePos+10)
 
 	// get tuning keys
-	hc.tuningTopLeft = string(lines[1][datePos+11 : datePos+13])
-	hc.tuningBotLeft = string(lines[2][datePos+11 : datePos+13])
-	hc.tuningTopMid = string(lines[1][datePos+13 : datePos+15])
-	hc.tuningBotMid = string(lines[2][datePos+13 : datePos+15])
-	hc.tuningTopRight = string(lines[1][datePos+15 : datePos+17])
-	hc.tuningBotRight = string(lines[2][datePos+15 : datePos+17])
+	hc.tuningTopLeft = sliceColumns(lines[1], datePos+11, datePos+13)
+	hc.tuningBotLeft = sliceColumns(lines[2], datePos+11, datePos+13)
+	hc.tuningTopMid = sliceColumns(lines[1], datePos+13, datePos+15)
+	hc.tuningBotMid = sliceColumns(lines[2], datePos+13, datePos+15)
+	hc.tuningTopRight = sliceColumns(lines[1], datePos+15, datePos+17)
+	hc.tuningBotRight = sliceColumns(lines[2], datePos+15, datePos+17)
 
 	return lines[4:], hc, nil
 }
 
+// headerDatePos is the least display column of "DATE:" within a formatted header
+const headerDatePos = 20
+
+// formatHeader returns the header lines holding the content, the inverse
+// of parseHeader. Each field must fit within its columns (ex. 3 for the bpm).
+func formatHeader(hc headerContentFilled) []string {
+	line2Prefix := ""
+	if hc.titleLine2 != "" {
+		line2Prefix = hc.titleLine2 + " |"
+	}
+	datePos := headerDatePos
+	for _, prefix := range []string{hc.title, line2Prefix} {
+		if displayWidth(prefix)+2 > datePos {
+			datePos = displayWidth(prefix) + 2
+		}
+	}
+
+	// set the field between the display columns relative to "DATE:"
+	set := func(line string, start, end int, value string) string {
+		return replaceColumns(line, datePos+start, datePos+end, fmt.Sprintf("%-*v", end-start, value))
+	}
+	line1 := replaceColumns(hc.title, datePos, datePos, "DATE:"+hc.date)
+	line2 := set(line2Prefix, 0, 1, hc.timesigTop)
+	line2 = set(line2, 2, 5, hc.bpm)
+	line2 = set(line2, 11, 13, hc.tuningTopLeft)
+	line2 = set(line2, 13, 15, hc.tuningTopMid)
+	line2 = set(line2, 15, 17, hc.tuningTopRight)
+	line3 := set("", 0, 1, hc.timesigBottom)
+	line3 = set(line3, 8, 10, hc.capo)
+	line3 = set(line3, 11, 13, hc.tuningBotLeft)
+	line3 = set(line3, 13, 15, hc.tuningBotMid)
+	line3 = set(line3, 15, 17, hc.tuningBotRight)
+	return []string{line1, strings.TrimRight(line2, " "), strings.TrimRight(line3, " "), ""}
+}
+
 func printHeaderFilled(pdf *gofpdf.Fpdf, bnd bounds, hc *headerContentFilled) (reducedBounds bounds) {
-	dateRightOffset := 2.3
+	// the header is laid out for a letter page, shrink it for narrower pages
+	scale := headerScale(bnd)
+	dateRightOffset := 2.3 * scale
+	dateFontPt, tuningFontPt := 14*scale, 9*scale
 
 	// flip string orientation if called for
 	if mirrorStringsOrderFlag {
-		thicknessesRev := make([]float64, len(thicknesses))
-		j := len(thicknesses) - 1
-		for i := 0; i < len(thicknesses); i++ {
-			thicknessesRev[j] = thicknesses[i]
-			j--
-		}
-		thicknesses = thicknessesRev
+		mirrorThicknesses()
 	}
 
 	// print date
-	pdf.SetFont("courier", "", 14)
-	fontH := GetFontHeight(14)
+	pdf.SetFont(fontFamily, "", dateFontPt)
+	fontH := GetFontHeight(dateFontPt)
 	fontW := GetCourierFontWidthFromHeight(fontH)
-	pdf.Text(bnd.right-dateRightOffset, bnd.top+padding-0.5*fontH, "DATE:"+hc.date)
+	pdf.Text(bnd.right-dateRightOffset, bnd.top+padding-0.5*fontH, encodeText("DATE:"+hc.date))
 
-	pdf.Text(bnd.right-dateRightOffset, bnd.top+padding+1.3*fontH, hc.timesigTop)
-	pdf.Text(bnd.right-dateRightOffset, bnd.top+padding+2.5*fontH, hc.timesigBottom)
+	pdf.Text(bnd.right-dateRightOffset, bnd.top+padding+1.3*fontH, encodeText(hc.timesigTop))
+	pdf.Text(bnd.right-dateRightOffset, bnd.top+padding+2.5*fontH, encodeText(hc.timesigBottom))
 	frX1 := bnd.right - dateRightOffset
 	frX2 := frX1 + fontW
 	frY := bnd.top + padding + 1.5*fontH
 	pdf.SetLineWidth(thinLW)
 	pdf.Line(frX1, frY, frX2, frY) // fraction line
 
-	pdf.Text(bnd.right-dateRightOffset, bnd.top+padding+1.3*fontH, "  "+hc.bpm)
+	pdf.Text(bnd.right-dateRightOffset, bnd.top+padding+1.3*fontH, encodeText("  "+hc.bpm))
 	pdf.Text(bnd.right-dateRightOffset, bnd.top+padding+2.5*fontH, "  BPM")
 
 	// print capo
-	pdf.Text(bnd.right-dateRightOffset, bnd.top+padding+2.5*fontH, "       "+hc.capo)
+	pdf.Text(bnd.right-dateRightOffset, bnd.top+padding+2.5*fontH, encodeText("       "+hc.capo))
 	pdf.SetLineWidth(thickerLW)
 	x1 := bnd.right - dateRightOffset + 6*fontW
 	x15 := bnd.right - dateRightOffset + 6.25*fontW
@@ -162,16 +195,16 @@ func printHeaderFilled(pdf *gofpdf.Fpdf, bnd bounds, hc *headerContentFilled) (r
 	}
 
 	// tuning information
-	pdf.SetFont("courier", "", 9)
-	tuningFontH := GetFontHeight(9)
+	pdf.SetFont(fontFamily, "", tuningFontPt)
+	tuningFontH := GetFontHeight(tuningFontPt)
 	tuningFontW := GetCourierFontWidthFromHeight(tuningFontH)
 
-	pdf.Text(keyXPos[0]-1.5*tuningFontW, yHeadTop+1.25*tuningFontH, hc.tuningTopLeft)
-	pdf.Text(keyXPos[1]-1.5*tuningFontW, yHeadTop+1.25*tuningFontH, hc.tuningTopMid)
-	pdf.Text(keyXPos[2]-1.5*tuningFontW, yHeadTop+1.25*tuningFontH, hc.tuningTopRight)
-	pdf.Text(keyXPos[0]-1.5*tuningFontW, yHeadBot-0.45*tuningFontH, hc.tuningBotLeft)
-	pdf.Text(keyXPos[1]-1.5*tuningFontW, yHeadBot-0.45*tuningFontH, hc.tuningBotMid)
-	pdf.Text(keyXPos[2]-1.5*tuningFontW, yHeadBot-0.45*tuningFontH, hc.tuningBotRight)
+	pdf.Text(keyXPos[0]-1.5*tuningFontW, yHeadTop+1.25*tuningFontH, encodeText(hc.tuningTopLeft))
+	pdf.Text(keyXPos[1]-1.5*tuningFontW, yHeadTop+1.25*tuningFontH, encodeText(hc.tuningTopMid))
+	pdf.Text(keyXPos[2]-1.5*tuningFontW, yHeadTop+1.25*tuningFontH, encodeText(hc.tuningTopRight))
+	pdf.Text(keyXPos[0]-1.5*tuningFontW, yHeadBot-0.45*tuningFontH, encodeText(hc.tuningBotLeft))
+	pdf.Text(keyXPos[1]-1.5*tuningFontW, yHeadBot-0.45*tuningFontH, encodeText(hc.tuningBotMid))
+	pdf.Text(keyXPos[2]-1.5*tuningFontW, yHeadBot-0.45*tuningFontH, encodeText(hc.tuningBotRight))
 
 	////////////////////////
 	// print title
@@ -183,8 +216,8 @@ func printHeaderFilled(pdf *gofpdf.Fpdf, bnd bounds, hc *headerContentFilled) (r
 	for {
 		titleFontH = 1.1 * GetFontHeight(titleFont)
 		titleFontW = GetCourierFontWidthFromHeight(titleFontH)
-		usedWidth1 := float64(len(hc.title)) * titleFontW
-		usedWidth2 := float64(len(hc.titleLine2)) * titleFontW
+		usedWidth1 := float64(displayWidth(hc.title)) * titleFontW
+		usedWidth2 := float64(displayWidth(hc.titleLine2)) * titleFontW
 		usedHeight = titleFontH
 		if len(hc.titleLine2) > 0 {
 			usedHeight += usedHeight
@@ -198,13 +231,13 @@ func printHeaderFilled(pdf *gofpdf.Fpdf, bnd bounds, hc *headerContentFilled) (r
 		break
 	}
 
-	pdf.SetFont("courier", "", titleFont)
+	pdf.SetFont(fontFamily, "", titleFont)
 	excess := availableHeight - usedHeight
 	if len(hc.titleLine2) == 0 {
-		pdf.Text(bnd.left, bnd.top+usedHeight+excess/2, hc.title)
+		pdf.Text(bnd.left, bnd.top+usedHeight+excess/2, encodeText(hc.title))
 	} else {
-		pdf.Text(bnd.left, bnd.top+titleFontH+excess/2, hc.title)
-		pdf.Text(bnd.left, bnd.top+2*titleFontH+excess/2, hc.titleLine2)
+		pdf.Text(bnd.left, bnd.top+titleFontH+excess/2, encodeText(hc.title))
+		pdf.Text(bnd.left, bnd.top+2*titleFontH+excess/2, encodeText(hc.titleLine2))
 	}
 
 	return bounds{yHeadBot + keyH + padding, bnd.left, bnd.bottom, bnd.right}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatHeaderRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		hc   headerContentFilled
+	}{
+		{
+			name: "all fields",
+			hc: headerContentFilled{
+				title: "My Song", titleLine2: "second", date: "2021-01-01",
+				tuningTopLeft: "E", tuningTopMid: "A", tuningTopRight: "D",
+				tuningBotLeft: "G", tuningBotMid: "B", tuningBotRight: "E",
+				capo: "2", bpm: "120", timesigTop: "3", timesigBottom: "4",
+			},
+		},
+		{
+			name: "empty fields",
+			hc:   headerContentFilled{title: "Sparse", date: "2021-01-01"},
+		},
+		{
+			name: "long and wide titles",
+			hc: headerContentFilled{
+				title: "A Title Long Enough To Push The Date", titleLine2: "日本語の副題",
+				date: "2021-01-01", capo: "10", bpm: "60", tuningTopLeft: "D#",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			lines := formatHeader(tc.hc)
+			reduced, hc, err := parseHeader(append(lines, "body"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reduced) != 1 || reduced[0] != "body" {
+				t.Errorf("got body %q, want the one body line", reduced)
+			}
+			got, want := headerFields(hc), headerFields(tc.hc)
+			for name, w := range want {
+				if got[name] != w {
+					t.Errorf("%v: got %q, want %q\n%v", name, got[name], w, strings.Join(lines, "\n"))
+				}
+			}
+		})
+	}
+}
+
+// headerFields returns the trimmed fields of the header by name
+func headerFields(hc headerContentFilled) map[string]string {
+	fields := map[string]string{
+		"title": hc.title, "titleLine2": hc.titleLine2, "date": hc.date,
+		"tuningTopLeft": hc.tuningTopLeft, "tuningTopMid": hc.tuningTopMid,
+		"tuningTopRight": hc.tuningTopRight, "tuningBotLeft": hc.tuningBotLeft,
+		"tuningBotMid": hc.tuningBotMid, "tuningBotRight": hc.tuningBotRight,
+		"capo": hc.capo, "bpm": hc.bpm,
+		"timesigTop": hc.timesigTop, "timesigBottom": hc.timesigBottom,
+	}
+	for name, value := range fields {
+		fields[name] = strings.TrimSpace(value)
+	}
+	return fields
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// a {name: value} or {name} directive on a line of its own
+	chordproDirectiveRe = regexp.MustCompile(`^\{\s*([A-Za-z_-]+)\s*(?:[:\s]\s*(.*?))?\s*\}$`)
+	chordproChordRe     = regexp.MustCompile(`\[([^\]]*)\]`)
+)
+
+// importChordPro converts the ChordPro chord sheet into the lines of a
+// songsheet, the title of the song defaults to the default title
+func importChordPro(content, defaultTitle string, date time.Time) (lines []string, title string) {
+	hc := headerContentFilled{
+		title:          defaultTitle,
+		date:           date.Format("2006-01-02"),
+		tuningTopLeft:  "E",
+		tuningTopMid:   "A",
+		tuningTopRight: "D",
+		tuningBotLeft:  "G",
+		tuningBotMid:   "B",
+		tuningBotRight: "E",
+	}
+	directives, body := []string{}, []string{}
+	artist := ""
+	inTab := false // tab and grid lines are kept as comments
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			continue // chordpro comment
+		}
+
+		m := chordproDirectiveRe.FindStringSubmatch(trimmed)
+		if m == nil {
+			switch {
+			case inTab && trimmed != "":
+				body = append(body, commentPrefix+" "+line)
+			case chordproChordRe.MatchString(line):
+				body = append(body, chordProSine(line)...)
+			default:
+				body = append(body, line)
+			}
+			continue
+		}
+
+		name, value := strings.ToLower(m[1]), m[2]
+		switch {
+		case name == "title" || name == "t":
+			hc.title = value
+		case name == "subtitle" || name == "st":
+			hc.titleLine2 = value
+		case name == "artist":
+			artist = value
+		case name == "capo" || name == "tempo" || name == "time":
+			if !setChordProHeaderField(&hc, name, value) {
+				// kept so the draft doesn't lose it
+				body = append(body, commentPrefix+" "+trimmed+" (doesn't fit the header)")
+			}
+		case name == "key":
+			directives = append(directives, formatDirective("KEY", value))
+		case name == "comment" || name == "c" || name == "comment_italic" ||
+			name == "ci" || name == "comment_box" || name == "cb":
+
+			body = append(body, commentPrefix+" "+value)
+		case strings.HasPrefix(name, "start_of_") || len(name) == 3 && strings.HasPrefix(name, "so"):
+			section := strings.Trim(strings.TrimPrefix(value, "label="), `"`)
+			if section == "" {
+				section = chordProSectionName(name)
+			}
+			inTab = strings.HasSuffix(name, "tab") || strings.HasSuffix(name, "grid") ||
+				name == "sot" || name == "sog"
+			body = append(body, formatDirective(sectionDirectiveKey, section))
+		case strings.HasPrefix(name, "end_of_") || len(name) == 3 && strings.HasPrefix(name, "eo"):
+			inTab = false
+		default:
+			body = append(body, commentPrefix+" "+trimmed)
+		}
+	}
+	if hc.titleLine2 == "" {
+		hc.titleLine2 = artist
+	}
+
+	// drop the blank lines at the ends of the body
+	for len(body) > 0 && strings.TrimSpace(body[0]) == "" {
+		body = body[1:]
+	}
+	for len(body) > 0 && strings.TrimSpace(body[len(body)-1]) == "" {
+		body = body[:len(body)-1]
+	}
+
+	lines = append(formatHeader(hc), directives...)
+	lines = append(lines, body...)
+	return append(lines, ""), hc.title
+}
+
+// setChordProHeaderField sets the header field of the capo, tempo, or time
+// directive, returning false if the value doesn't fit within the columns of
+// the header field
+func setChordProHeaderField(hc *headerContentFilled, name, value string) bool {
+	switch name {
+	case "capo":
+		if displayWidth(value) > 2 {
+			return false
+		}
+		hc.capo = value
+	case "tempo":
+		if displayWidth(value) > 3 {
+			return false
+		}
+		hc.bpm = value
+	case "time":
+		splt := strings.Split(value, "/")
+		if len(splt) != 2 || displayWidth(splt[0]) != 1 || displayWidth(splt[1]) != 1 {
+			return false
+		}
+		hc.timesigTop, hc.timesigBottom = splt[0], splt[1]
+	}
+	return true
+}
+
+// chordProSectionName names the section of the start directive
+// (ex. start_of_chorus or soc)
+func chordProSectionName(name string) string {
+	if strings.HasPrefix(name, "start_of_") {
+		return strings.TrimPrefix(name, "start_of_")
+	}
+	switch name {
+	case "soc":
+		return "chorus"
+	case "sov":
+		return "verse"
+	case "sob":
+		return "bridge"
+	case "sot":
+		return "tab"
+	case "sog":
+		return "grid"
+	}
+	return "section"
+}
+
+// chordProSine converts the line with inline chords into a sine holding the
+// chords along its axis followed by the lyrics (if any) under the sine. Where
+// chords would run into each other the lyrics are spaced out to fit them.
+func chordProSine(line string) (lines []string) {
+	axis, lyric := "", ""
+	for {
+		loc := chordproChordRe.FindStringSubmatchIndex(line)
+		if loc == nil {
+			lyric += line
+			break
+		}
+		lyric += line[:loc[0]]
+		chord := strings.TrimPrefix(strings.TrimSpace(line[loc[2]:loc[3]]), "*")
+		line = line[loc[1]:]
+		if chord == "" {
+			continue
+		}
+
+		// keep a space between the chords
+		col := displayWidth(lyric)
+		if axisWidth := displayWidth(axis); axisWidth > 0 && col <= axisWidth {
+			pad := " "
+			if lyric != "" && strings.TrimRight(lyric, " ") == lyric && line != "" && line[0] != ' ' {
+				pad = "-" // within a word
+			}
+			lyric += strings.Repeat(pad, axisWidth+1-col)
+			col = axisWidth + 1
+		}
+		axis += strings.Repeat(" ", col-displayWidth(axis)) + chord
+	}
+	lyric = strings.TrimRight(lyric, " ")
+	if axis == "" {
+		return []string{lyric} // only empty chords
+	}
+
+	width := displayWidth(axis)
+	if displayWidth(lyric) > width {
+		width = displayWidth(lyric)
+	}
+	humps := int(math.Ceil(float64(width) / charsToaHump))
+	if humps < 1 {
+		humps = 1
+	}
+	lines = []string{
+		axis,
+		strings.TrimRight(strings.Repeat("_   ", humps), " "),
+		strings.Repeat(" \\_/", humps),
+		"",
+	}
+	if strings.TrimSpace(lyric) != "" {
+		lines = append(lines, lyric)
+	}
+	return lines
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChordProSine(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantAxis  string
+		wantLyric string
+		wantHumps float64
+		wantCols  []int // of the chords along the axis
+	}{
+		{
+			name:      "chords over lyrics",
+			line:      "A[G]mazing [C]grace",
+			wantAxis:  " G      C",
+			wantLyric: "Amazing grace",
+			wantHumps: 4,
+			wantCols:  []int{1, 8},
+		},
+		{
+			name:      "chords running together",
+			line:      "[G][C]lost",
+			wantAxis:  "G C",
+			wantLyric: "  lost",
+			wantHumps: 2,
+			wantCols:  []int{0, 2},
+		},
+		{
+			name:      "chords within a word",
+			line:      "he[Am]l[D]lo",
+			wantAxis:  "  Am D",
+			wantLyric: "hel--lo",
+			wantHumps: 2,
+			wantCols:  []int{2, 5},
+		},
+		{
+			name:      "chords only",
+			line:      "[G] [C]",
+			wantAxis:  "G C",
+			wantLyric: "",
+			wantHumps: 1,
+			wantCols:  []int{0, 2},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			lines := chordProSine(tc.line)
+			if lines[0] != tc.wantAxis {
+				t.Errorf("got axis %q, want %q", lines[0], tc.wantAxis)
+			}
+			lyric := ""
+			if len(lines) > 4 {
+				lyric = lines[4]
+			}
+			if lyric != tc.wantLyric {
+				t.Errorf("got lyric %q, want %q", lyric, tc.wantLyric)
+			}
+
+			_, elem, err := sine{}.parseText(lines)
+			if err != nil {
+				t.Fatalf("sine not accepted: %v\n%v", err, strings.Join(lines, "\n"))
+			}
+			s := elem.(sine)
+			if s.humps != tc.wantHumps {
+				t.Errorf("got %v humps, want %v", s.humps, tc.wantHumps)
+			}
+			cols := []int{}
+			for _, sa := range s.alongAxis {
+				if sa.isChord() {
+					cols = append(cols, int(sa.position*charsToaHump))
+				}
+			}
+			if len(cols) != len(tc.wantCols) {
+				t.Fatalf("got chords at %v, want %v", cols, tc.wantCols)
+			}
+			for i := range cols {
+				if cols[i] != tc.wantCols[i] {
+					t.Errorf("got chords at %v, want %v", cols, tc.wantCols)
+				}
+			}
+		})
+	}
+}
+
+func TestImportChordPro(t *testing.T) {
+	content := `# a comment
+{title: Song}
+{artist: Someone}
+{key: G}
+{capo: 2}
+{tempo: 120.5}
+{time: 12/8}
+
+{start_of_chorus}
+I [G]once was [C]lost
+{end_of_chorus}
+{c: slower}`
+	lines, title := importChordPro(content, "file", time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC))
+	if title != "Song" {
+		t.Errorf("got title %q, want Song", title)
+	}
+	ss, err := parseSongsheet([]byte(strings.Join(lines, "\n")), "")
+	if err != nil {
+		t.Fatalf("%v\n%v", err, strings.Join(lines, "\n"))
+	}
+	if ss.hc.titleLine2 != "Someone" || ss.hc.date != "2021-01-02" ||
+		strings.TrimSpace(ss.hc.capo) != "2" || strings.TrimSpace(ss.hc.bpm) != "" {
+		t.Errorf("unexpected header %+v", ss.hc)
+	}
+
+	for _, want := range []string{
+		"// KEY=G",
+		"// SECTION=chorus",
+		"// {tempo: 120.5} (doesn't fit the header)",
+		"// {time: 12/8} (doesn't fit the header)",
+		"// slower",
+	} {
+		found := false
+		for _, line := range lines {
+			found = found || line == want
+		}
+		if !found {
+			t.Errorf("missing line %q\n%v", want, strings.Join(lines, "\n"))
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
-func getLasses(lines []string) (lasses lineAndSasses) {
-	el := sine{} // dummy element to make the call
+// getLasses returns every sine of the lines along with its line number, the
+// playback times are those of the take
+func getLasses(lines []string, take string) (lasses lineAndSasses) {
+	el := sine{take: take} // dummy element to make the call
 	for yI := 0; yI < len(lines); yI++ {
 		workingLines := lines[yI:]
 		_, sasEl, err := el.parseText(workingLines)
@@ -29,25 +31,153 @@ type lineAndSas struct {
 
 type lineAndSasses []lineAndSas
 
-// gets the next position of the cursor which is moving hump-movements
-// along the text-sine-curve. If there are not enough positions within
-// the current hump, then recurively call for the next hump
-func (ls lineAndSasses) getNextPosition(startCurX, startHumpIndex, charMovement int) (
-	endCurX, endCurY int, endReached bool) {
+// charPos is a single character position along the text-sine-curves
+type charPos struct {
+	lassesIndex int // index of the lineAndSas the character belongs to
+	col         int // character position along the text-sine-curve
+	hasPT       bool
+	pt          playbackTime
+}
+
+// charPositions converts the sasses into an array of characters
+func (ls lineAndSasses) charPositions() (charPoss []charPos) {
+	for i, s := range ls {
+		maxChars := int((s.sas.totalHumps() * charsToaHump) + 0.00001) // float rounding
+		for j := 0; j < maxChars; j++ {
+			cp := charPos{lassesIndex: i, col: j}
+			for _, spt := range s.sas.playbackTimes {
+				if spt.charPosition == j {
+					cp.hasPT = true
+					cp.pt = spt.pt
+				}
+			}
+			charPoss = append(charPoss, cp)
+		}
+	}
+	return charPoss
+}
+
+// cursorCharIndex returns the index within the charPoss of the cursor
+// position (starting from 1, with comments already deleted), the cursor is
+// fit to the nearest text-sine-curve
+func (ls lineAndSasses) cursorCharIndex(charPoss []charPos, curX, curY int) int {
+
+	// determine the sas belonging to the cursor
+	curI := 0
+LOOP:
+	for i, s := range ls {
+		switch {
+		case (curY - 1) == int(s.lineNo):
+			curI = i
+			break LOOP
+		case i == 0 && (curY-1) < int(s.lineNo):
+			curI = 0
+			break LOOP
+		case i > 0 && (curY-1) < int(s.lineNo):
+			curI = i - 1
+			break LOOP
+		case i == len(ls)-1 && (curY-1) > int(s.lineNo):
+			curI = i
+			break LOOP
+		}
+	}
+
+	// clamp the cursor within the sas
+	first, last := -1, -1
+	for i, cp := range charPoss {
+		if cp.lassesIndex != curI {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+		if cp.col == curX-1 {
+			return i
+		}
+	}
+	switch {
+	case first < 0:
+		return 0
+	case curX-1 > charPoss[last].col:
+		return last
+	}
+	return first
+}
+
+// totalHumps returns the number of humps within all of the sines
+func (ls lineAndSasses) totalHumps() (humps float64) {
+	for _, s := range ls {
+		humps += s.sas.totalHumps()
+	}
+	return humps
+}
 
-	if startHumpIndex >= len(ls) {
-		return 0, 0, true
+// lastPlaybackTime returns the final playback time of all the sines
+// along with the number of humps which follow it
+func (ls lineAndSasses) lastPlaybackTime() (pt playbackTime, humpsAfter float64, found bool) {
+	for i := len(ls) - 1; i >= 0; i-- {
+		s := ls[i].sas
+		if !s.hasPlaybackTime() {
+			humpsAfter += s.totalHumps()
+			continue
+		}
+		last := s.playbackTimes[len(s.playbackTimes)-1]
+		humpsAfter += s.totalHumps() - float64(last.charPosition)/charsToaHump
+		return last.pt, humpsAfter, true
 	}
+	return pt, 0, false
+}
 
-	// get the current line hump
-	clh := ls[startHumpIndex].sas.totalHumps()
+// timedSine is a sine of the song along with its position in the song
+type timedSine struct {
+	sas   sine
+	start float64 // humps of the song preceding this sine
+}
 
-	if startCurX+charMovement <= int(clh*charsToaHump) {
-		endCurX = startCurX + charMovement
-		endCurY = int(ls[startHumpIndex].lineNo) + lineNoOffsetToMiddleHump
-		return endCurX, endCurY, false
+// end returns the position in humps at which the sine finishes
+func (ts timedSine) end() float64 {
+	return ts.start + ts.sas.totalHumps()
+}
+
+// songSines returns all the sines of the song in order
+func songSines(elems []tssElement) (sines []timedSine) {
+	humps := 0.0
+	for _, el := range elems {
+		if s, ok := el.(sine); ok {
+			sines = append(sines, timedSine{s, humps})
+			humps += s.totalHumps()
+		}
 	}
+	return sines
+}
 
-	reducedCM := (startCurX + charMovement - int(clh*charsToaHump))
-	return ls.getNextPosition(0, startHumpIndex+1, reducedCM)
+// elemLasses returns the sines of the elements in order, along with the
+// index of the element of each (the line numbers are left as the indexes
+// of the elements)
+func elemLasses(elems []tssElement) (lasses lineAndSasses, elemIndexes []int) {
+	for i, el := range elems {
+		if s, ok := el.(sine); ok {
+			lasses = append(lasses, lineAndSas{int16(i), s})
+			elemIndexes = append(elemIndexes, i)
+		}
+	}
+	return lasses, elemIndexes
+}
+
+// playbackMarker is a playback time positioned within the whole song
+type playbackMarker struct {
+	humps float64 // position in humps from the start of the song
+	pt    playbackTime
+}
+
+// songPlaybackMarkers returns all the playback times of the song in order
+func songPlaybackMarkers(sines []timedSine) (markers []playbackMarker) {
+	for _, ts := range sines {
+		for _, spt := range ts.sas.playbackTimes {
+			markers = append(markers, playbackMarker{
+				ts.start + float64(spt.charPosition)/charsToaHump, spt.pt})
+		}
+	}
+	return markers
 }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// json-rpc 2.0 messages as framed by the language server protocol
+// (a Content-Length header followed by the json body)
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"` // absent for notifications
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+// readRPCMessage reads the next framed message
+func readRPCMessage(r *bufio.Reader) (msg rpcMessage, err error) {
+	contentLength := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return msg, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		splt := strings.SplitN(line, ":", 2)
+		if len(splt) == 2 && strings.EqualFold(strings.TrimSpace(splt[0]), "Content-Length") {
+			contentLength, err = strconv.Atoi(strings.TrimSpace(splt[1]))
+			if err != nil {
+				return msg, fmt.Errorf("bad Content-Length header: %v", line)
+			}
+		}
+	}
+	if contentLength < 0 {
+		return msg, errors.New("missing Content-Length header")
+	}
+
+	body := make([]byte, contentLength)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return msg, err
+	}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return msg, &rpcError{rpcParseError, err.Error()}
+	}
+	return msg, nil
+}
+
+// writeRPCMessage writes the message with its Content-Length header
+func writeRPCMessage(w io.Writer, msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+// language server protocol types (only the fields used)
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"` // in utf-16 code units
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type lspTextDocumentPositionParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	Position     lspPosition               `json:"position"`
+}
+
+type lspDidOpenParams struct {
+	TextDocument struct {
+		URI     string `json:"uri"`
+		Version int    `json:"version"`
+		Text    string `json:"text"`
+	} `json:"textDocument"`
+}
+
+type lspDidChangeParams struct {
+	TextDocument struct {
+		URI     string `json:"uri"`
+		Version int    `json:"version"`
+	} `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"` // the full text, only full syncing is supported
+	} `json:"contentChanges"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+const (
+	lspSeverityError   = 1
+	lspSeverityWarning = 2
+)
+
+type lspPublishDiagnosticsParams struct {
+	URI         string          `json:"uri"`
+	Version     int             `json:"version"`
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+type lspMarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type lspHover struct {
+	Contents lspMarkupContent `json:"contents"`
+	Range    *lspRange        `json:"range,omitempty"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+// utf16ToColumn converts the utf-16 character offset of the line into the
+// display column used throughout the songsheet parsers
+func utf16ToColumn(line string, character int) (col int) {
+	units := 0
+	for _, r := range line {
+		if units >= character {
+			break
+		}
+		units += len(utf16.Encode([]rune{r}))
+		col += runeWidth(r)
+	}
+	return col
+}
+
+// columnToUTF16 converts the display column of the line into the utf-16
+// character offset, columns beyond the end of the line are kept beyond it
+func columnToUTF16(line string, col int) (character int) {
+	width := 0
+	for _, r := range line {
+		if width >= col {
+			return character
+		}
+		width += runeWidth(r)
+		character += len(utf16.Encode([]rune{r}))
+	}
+	return character + col - width
+}
+
+// utf16Len returns the length of the string in utf-16 code units
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// uriToPath returns the filepath of a file uri
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return u.Path
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+)
+
+// minimal standard midi file (type 1) writer
+
+const midiTicksPerBeat = 480
+
+type midiEvent struct {
+	tick  uint32
+	order int // ordering of events on the same tick (note offs first)
+	data  []byte
+}
+
+type midiTrack struct {
+	events []midiEvent
+}
+
+func (t *midiTrack) add(tick uint32, order int, data ...byte) {
+	t.events = append(t.events, midiEvent{tick, order, data})
+}
+
+func (t *midiTrack) name(name string) {
+	t.meta(0, 0x03, []byte(name))
+}
+
+func (t *midiTrack) meta(tick uint32, kind byte, data []byte) {
+	ev := append([]byte{0xff, kind}, midiVarLen(uint32(len(data)))...)
+	t.add(tick, 0, append(ev, data...)...)
+}
+
+// tempo sets the tempo in beats per minute from the tick onwards
+func (t *midiTrack) tempo(tick uint32, bpm float64) {
+	usPerBeat := uint32(60000000 / bpm)
+	t.meta(tick, 0x51, []byte{byte(usPerBeat >> 16), byte(usPerBeat >> 8), byte(usPerBeat)})
+}
+
+// timeSignature sets the time signature, the denominator must be a power of 2
+func (t *midiTrack) timeSignature(tick uint32, num, denom int) {
+	pow := 0
+	for d := denom; d > 1; d /= 2 {
+		pow++
+	}
+	t.meta(tick, 0x58, []byte{byte(num), byte(pow), 24, 8})
+}
+
+func (t *midiTrack) program(channel, program byte) {
+	t.add(0, 0, 0xc0|channel, program)
+}
+
+func (t *midiTrack) note(channel byte, start, end uint32, note, velocity byte) {
+	t.add(start, 2, 0x90|channel, note, velocity)
+	t.add(end, 1, 0x80|channel, note, 0)
+}
+
+func (t *midiTrack) bytes() []byte {
+	sort.SliceStable(t.events, func(i, j int) bool {
+		if t.events[i].tick == t.events[j].tick {
+			return t.events[i].order < t.events[j].order
+		}
+		return t.events[i].tick < t.events[j].tick
+	})
+
+	var buf bytes.Buffer
+	lastTick := uint32(0)
+	for _, ev := range t.events {
+		buf.Write(midiVarLen(ev.tick - lastTick))
+		buf.Write(ev.data)
+		lastTick = ev.tick
+	}
+	buf.Write([]byte{0x00, 0xff, 0x2f, 0x00}) // end of track
+	return buf.Bytes()
+}
+
+// writeMIDI returns the standard midi file containing the tracks
+func writeMIDI(tracks []*midiTrack) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("MThd")
+	_ = binary.Write(&buf, binary.BigEndian, uint32(6))
+	_ = binary.Write(&buf, binary.BigEndian, uint16(1)) // format
+	_ = binary.Write(&buf, binary.BigEndian, uint16(len(tracks)))
+	_ = binary.Write(&buf, binary.BigEndian, uint16(midiTicksPerBeat))
+	for _, t := range tracks {
+		data := t.bytes()
+		buf.WriteString("MTrk")
+		_ = binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+		buf.Write(data)
+	}
+	return buf.Bytes()
+}
+
+// midiVarLen encodes the value as a midi variable length quantity
+func midiVarLen(v uint32) []byte {
+	out := []byte{byte(v & 0x7f)}
+	for v >>= 7; v > 0; v >>= 7 {
+		out = append([]byte{byte(v&0x7f) | 0x80}, out...)
+	}
+	return out
+}
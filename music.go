@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// semitones of each note name above C
+var noteSemitones = map[rune]int{
+	'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11,
+}
+
+var (
+	majorScale = []int{0, 2, 4, 5, 7, 9, 11}
+	minorScale = []int{0, 2, 3, 5, 7, 8, 10}
+)
+
+// midi note numbers of the open guitar strings (standard tuning)
+// from thick to thin
+var openStringNotes = []int{40, 45, 50, 55, 59, 64}
+
+type musicKey struct {
+	root  int // semitones above C
+	minor bool
+}
+
+// parseKey parses a key such as "G", "F#", "Bb", "Am", or "C#min"
+func parseKey(str string) (key musicKey, err error) {
+	str = strings.TrimSpace(str)
+	root, rest, ok := parseNoteName(str)
+	if !ok {
+		return key, fmt.Errorf("could not parse key: %v", str)
+	}
+	key.root = root
+	switch strings.ToLower(rest) {
+	case "", "maj", "major":
+	case "m", "min", "minor":
+		key.minor = true
+	default:
+		return key, fmt.Errorf("could not parse key: %v", str)
+	}
+	return key, nil
+}
+
+// parseNoteName parses the note name at the start of the string along with
+// any sharp or flat, returning the remainder of the string
+func parseNoteName(str string) (semitones int, rest string, ok bool) {
+	if len(str) == 0 {
+		return 0, str, false
+	}
+	semitones, ok = noteSemitones[rune(str[0])]
+	if !ok {
+		return 0, str, false
+	}
+	rest = str[1:]
+	switch {
+	case strings.HasPrefix(rest, "#"):
+		semitones++
+		rest = rest[1:]
+	case strings.HasPrefix(rest, "b"):
+		semitones--
+		rest = rest[1:]
+	}
+	return (semitones + 12) % 12, rest, true
+}
+
+// scaleDegreeSemitones returns the semitones above the key root of the melody
+// number, numbers above 7 continue into the next octave and 0 is the seventh
+// degree of the octave below
+func (k musicKey) scaleDegreeSemitones(num int) int {
+	scale := majorScale
+	if k.minor {
+		scale = minorScale
+	}
+	if num == 0 {
+		return scale[6] - 12
+	}
+	return scale[(num-1)%7] + 12*((num-1)/7)
+}
+
+// melodyString returns the guitar string (0 being the thickest) which
+// the modifier of the melody calls for, as shown in the legend along the
+// side of the chord chart pillar
+func (m melody) melodyString() int {
+	switch m.modifier {
+	case mod3:
+		if m.modifierIsAboveNum {
+			return 0
+		}
+		return 5
+	case mod2:
+		if m.modifierIsAboveNum {
+			return 1
+		}
+		return 4
+	default:
+		if m.modifierIsAboveNum {
+			return 2
+		}
+		return 3
+	}
+}
+
+// midiNote returns the midi note number of the melody within the key,
+// played at or just above the open note of its guitar string
+func (m melody) midiNote(k musicKey) (note int, ok bool) {
+	if m.num < '0' || m.num > '9' {
+		return 0, false
+	}
+	open := openStringNotes[m.melodyString()]
+	pitchClass := k.root + k.scaleDegreeSemitones(int(m.num-'0'))
+	note = open + ((pitchClass-open)%12+12)%12
+	return note, true
+}
+
+// chord intervals (in semitones above the root) by chord quality
+var chordQualities = map[string][]int{
+	"":     {0, 4, 7},
+	"m":    {0, 3, 7},
+	"7":    {0, 4, 7, 10},
+	"m7":   {0, 3, 7, 10},
+	"maj7": {0, 4, 7, 11},
+	"6":    {0, 4, 7, 9},
+	"m6":   {0, 3, 7, 9},
+	"9":    {0, 4, 7, 10, 14},
+	"2":    {0, 2, 7},
+	"s":    {0, 5, 7},
+	"sus":  {0, 5, 7},
+	"4":    {0, 5, 7},
+	"d":    {0, 3, 6},
+	"dim":  {0, 3, 6},
+	"a":    {0, 4, 8},
+	"aug":  {0, 4, 8},
+	"5":    {0, 7},
+}
+
+// chordNotes returns the midi notes of a chord name such as "Am7", played
+// with the root in the octave below middle C
+func chordNotes(name string) (notes []int, ok bool) {
+	root, quality, ok := parseNoteName(name)
+	if !ok {
+		return nil, false
+	}
+	intervals, ok := chordQualities[quality]
+	if !ok {
+		return nil, false
+	}
+	for _, interval := range intervals {
+		notes = append(notes, 48+root+interval)
+	}
+	return notes, true
+}
+
+// chordName returns the full name of a chord annotated along the sine axis
+func (sa sineAnnotation) chordName() string {
+	name := string(sa.ch)
+	if sa.subscript != ' ' {
+		name += string(sa.subscript)
+	}
+	if sa.superscript != ' ' {
+		name += string(sa.superscript)
+	}
+	return name
+}
+
+// isChord returns whether the along axis annotation is a chord
+func (sa sineAnnotation) isChord() bool {
+	_, ok := noteSemitones[sa.ch]
+	return !sa.isMelody && ok
+}
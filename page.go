@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pageSizeFlag    string
+	orientationFlag string
+	marginFlag      float64
+
+	// user defaults for the page flags, each line of the form: key=value
+	//   page-size=A4
+	//   orientation=landscape
+	//   margin=0.5
+	configPath = os.ExpandEnv("$HOME/.songsheet_config")
+
+	// named page sizes in inches (portrait)
+	pageSizes = map[string]gofpdf.SizeType{
+		"letter": {Wd: 8.5, Ht: 11},
+		"legal":  {Wd: 8.5, Ht: 14},
+		"a4":     {Wd: 8.27, Ht: 11.69},
+		"a5":     {Wd: 5.83, Ht: 8.27},
+	}
+
+	// content width of a portrait letter page, everything in the
+	// headers was originally laid out for this width
+	letterContentWidth = 8.5 - padding
+)
+
+type pageLayout struct {
+	size      gofpdf.SizeType // portrait size in inches
+	landscape bool
+	margin    float64 // in inches
+}
+
+func registerPageFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(
+		&pageSizeFlag, "page-size", "Letter",
+		"page size: Letter, Legal, A4, A5, or custom WxH in inches (ex. 6x9)")
+	cmd.PersistentFlags().StringVar(
+		&orientationFlag, "orientation", "portrait",
+		"page orientation: portrait or landscape")
+	cmd.PersistentFlags().Float64Var(
+		&marginFlag, "margin", padding,
+		"page margin in inches")
+}
+
+// getPageLayout determines the page from the flags, falling back on the
+// user config for any flags which were not explicitly set
+func getPageLayout(cmd *cobra.Command) (pl pageLayout, err error) {
+	config, err := readConfig(configPath)
+	if err != nil {
+		return pl, err
+	}
+
+	flags := cmd.Flags()
+	sizeStr, orientation, marginStr :=
+		pageSizeFlag, orientationFlag, fmt.Sprintf("%v", marginFlag)
+	if v, ok := config["page-size"]; ok && !flags.Changed("page-size") {
+		sizeStr = v
+	}
+	if v, ok := config["orientation"]; ok && !flags.Changed("orientation") {
+		orientation = v
+	}
+	if v, ok := config["margin"]; ok && !flags.Changed("margin") {
+		marginStr = v
+	}
+
+	pl.size, err = parsePageSize(sizeStr)
+	if err != nil {
+		return pl, err
+	}
+
+	switch strings.ToLower(orientation) {
+	case "portrait", "p":
+		pl.landscape = false
+	case "landscape", "l":
+		pl.landscape = true
+	default:
+		return pl, fmt.Errorf("unknown orientation: %v", orientation)
+	}
+
+	pl.margin, err = strconv.ParseFloat(marginStr, 64)
+	if err != nil {
+		return pl, fmt.Errorf("bad margin: %v", err)
+	}
+	w, h := pl.dimensions()
+	if pl.margin < 0 || 2*pl.margin >= math.Min(w, h) {
+		return pl, fmt.Errorf("margin %v does not fit on the page", pl.margin)
+	}
+	return pl, nil
+}
+
+// parsePageSize parses either a named page size or a custom WxH size
+func parsePageSize(str string) (size gofpdf.SizeType, err error) {
+	if size, found := pageSizes[strings.ToLower(str)]; found {
+		return size, nil
+	}
+	splt := strings.SplitN(strings.ToLower(str), "x", 2)
+	if len(splt) != 2 {
+		return size, fmt.Errorf("unknown page size: %v", str)
+	}
+	size.Wd, err = strconv.ParseFloat(splt[0], 64)
+	if err != nil {
+		return size, fmt.Errorf("bad page width: %v", err)
+	}
+	size.Ht, err = strconv.ParseFloat(splt[1], 64)
+	if err != nil {
+		return size, fmt.Errorf("bad page height: %v", err)
+	}
+	if size.Wd <= 0 || size.Ht <= 0 {
+		return size, fmt.Errorf("page size must be positive: %v", str)
+	}
+	return size, nil
+}
+
+// dimensions returns the width and height of the page as printed
+func (pl pageLayout) dimensions() (width, height float64) {
+	if pl.landscape {
+		return pl.size.Ht, pl.size.Wd
+	}
+	return pl.size.Wd, pl.size.Ht
+}
+
+// newPdf creates a new pdf with the first page already added
+func (pl pageLayout) newPdf() *gofpdf.Fpdf {
+	orientation := "P"
+	if pl.landscape {
+		orientation = "L"
+	}
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		OrientationStr: orientation,
+		UnitStr:        "in",
+		Size:           pl.size,
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.AddPage()
+	return pdf
+}
+
+// bounds returns the printable area of the page. Elements only pad their
+// right and bottom sides, so the padding is added back to those edges.
+func (pl pageLayout) bounds() bounds {
+	w, h := pl.dimensions()
+	return bounds{pl.margin, pl.margin, h - pl.margin + padding, w - pl.margin + padding}
+}
+
+// headerScale is the factor by which header elements are shrunk to fit
+// within pages narrower than a portrait letter page
+func headerScale(bnd bounds) float64 {
+	return math.Min(1, bnd.Width()/letterContentWidth)
+}
+
+// readConfig reads the key=value lines of the config file,
+// a missing config file is treated as empty
+func readConfig(path string) (config map[string]string, err error) {
+	config = make(map[string]string)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		splt := strings.SplitN(line, "=", 2)
+		if len(splt) != 2 {
+			return config, fmt.Errorf("bad config line in %v: %v", path, line)
+		}
+		config[strings.TrimSpace(splt[0])] = strings.TrimSpace(splt[1])
+	}
+	return config, scanner.Err()
+}
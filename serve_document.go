@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ssDocument is a songsheet held open by the language server, parsed once
+// per change
+type ssDocument struct {
+	uri     string
+	version int
+	lines   []string // original lines (including comments)
+	take    string   // the default take, there being no flag
+
+	ss          songsheet
+	timeline    songTimeline // for the playbackTime and position requests
+	elemLines   []int        // original line index of each element
+	key         musicKey
+	keyFound    bool
+	diagnostics []lspDiagnostic
+}
+
+func newSSDocument(uri string, version int, text string) *ssDocument {
+	doc := &ssDocument{
+		uri:     uri,
+		version: version,
+		lines:   strings.Split(text, "\n"),
+	}
+	doc.take = defaultTake(doc.lines)
+	doc.parse()
+	return doc
+}
+
+// parse parses the songsheet collecting the diagnostics along the way
+func (doc *ssDocument) parse() {
+	doc.diagnostics = []lspDiagnostic{}
+	doc.timeline = newSongTimeline(doc.lines, doc.take)
+
+	if keyStr, found := getDirective(doc.lines, "KEY"); found {
+		key, err := parseKey(keyStr)
+		if err != nil {
+			index, _, _ := findDirective(doc.lines, "KEY")
+			doc.diagnose(index, lspSeverityError, err.Error())
+		} else {
+			doc.key, doc.keyFound = key, true
+		}
+	}
+
+	lines, origIndexes := deleteCommentsMapped(doc.lines)
+	body, hc, err := parseHeader(lines)
+	if err != nil {
+		doc.diagnose(0, lspSeverityError, err.Error())
+		return
+	}
+	doc.ss.hc = hc
+	doc.ss.lines = body
+	headerLen := len(lines) - len(body)
+	origIndex := func(bodyIndex int) int {
+		return origIndexes[headerLen+bodyIndex]
+	}
+
+	elems, starts, err := parseElems(body, doc.take)
+	doc.ss.elems = elems
+	doc.elemLines = nil
+	for _, start := range starts {
+		doc.elemLines = append(doc.elemLines, origIndex(start))
+	}
+	if perr, ok := err.(elemsParseError); ok {
+		doc.diagnose(origIndex(perr.lineIndex), lspSeverityError,
+			strings.Join(perr.allErrs, "; "))
+	}
+
+	// lyrics are the catch-all element, point out the lines which
+	// look like they were intended to be another element
+	for i, elem := range elems {
+		if _, ok := elem.(lyrics); !ok {
+			continue
+		}
+		if err := misparsedLyricError(body[starts[i]:]); err != nil {
+			doc.diagnose(doc.elemLines[i], lspSeverityWarning, err.Error())
+		}
+	}
+
+	var prevPT playbackTime
+	prevPTFound := false
+	for i, elem := range elems {
+		sas, ok := elem.(sine)
+		if !ok {
+			continue
+		}
+		for _, sa := range sas.alongAxis {
+			if !sa.isChord() {
+				continue
+			}
+			if _, ok := chordNotes(sa.chordName()); !ok {
+				doc.diagnoseCol(doc.elemLines[i], int(sa.position*charsToaHump),
+					lspSeverityWarning, fmt.Sprintf("unknown chord %v", sa.chordName()))
+			}
+		}
+		for _, spt := range sas.playbackTimes {
+			if prevPTFound && spt.pt.t.Before(prevPT.t) {
+				doc.diagnoseCol(origIndex(starts[i]+sas.ptLineOffset), spt.charPosition, lspSeverityError,
+					fmt.Sprintf("playback time %v is before the previous playback time %v",
+						spt.pt.str, prevPT.str))
+			}
+			prevPT, prevPTFound = spt.pt, true
+		}
+	}
+}
+
+// misparsedLyricError returns the error of the element which the lyric line
+// resembles, or nil if it looks like an ordinary lyric
+func misparsedLyricError(lines []string) error {
+	line := lines[0]
+	var err error
+	switch {
+	case strings.HasPrefix(line, "  |  |  |"):
+		_, _, err = chordChart{}.parseText(lines)
+		return elemError("chord chart", err)
+	case strings.HasPrefix(line, "_"), strings.HasPrefix(line, " \\_/"):
+		return elemError("sine", fmt.Errorf("missing the along axis line above the humps"))
+	case stringOnlyContainsNumbersAndSpaces(line):
+		_, _, err = melodies{}.parseText(lines)
+		return elemError("melody", err)
+	}
+	return nil
+}
+
+func elemError(kind string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("parsed as lyrics, not a %v: %v", kind, err)
+}
+
+// diagnose adds a diagnostic spanning the whole line
+func (doc *ssDocument) diagnose(lineIndex, severity int, msg string) {
+	doc.diagnostics = append(doc.diagnostics, lspDiagnostic{
+		Range:    doc.lineRange(lineIndex),
+		Severity: severity,
+		Source:   "songsheet",
+		Message:  msg,
+	})
+}
+
+// diagnoseCol adds a diagnostic for the single display column of the line
+func (doc *ssDocument) diagnoseCol(lineIndex, col, severity int, msg string) {
+	line := doc.line(lineIndex)
+	rng := lspRange{
+		Start: lspPosition{lineIndex, columnToUTF16(line, col)},
+		End:   lspPosition{lineIndex, columnToUTF16(line, col+1)},
+	}
+	doc.diagnostics = append(doc.diagnostics, lspDiagnostic{
+		Range:    rng,
+		Severity: severity,
+		Source:   "songsheet",
+		Message:  msg,
+	})
+}
+
+func (doc *ssDocument) line(lineIndex int) string {
+	if lineIndex < 0 || lineIndex >= len(doc.lines) {
+		return ""
+	}
+	return doc.lines[lineIndex]
+}
+
+func (doc *ssDocument) lineRange(lineIndex int) lspRange {
+	return lspRange{
+		Start: lspPosition{lineIndex, 0},
+		End:   lspPosition{lineIndex, utf16Len(doc.line(lineIndex))},
+	}
+}
+
+// elemAt returns the element the line belongs to along with the line offset
+// from the start of the element
+func (doc *ssDocument) elemAt(lineIndex int) (elem tssElement, offset int, found bool) {
+	for i := len(doc.elemLines) - 1; i >= 0; i-- {
+		if doc.elemLines[i] <= lineIndex {
+			return doc.ss.elems[i], lineIndex - doc.elemLines[i], true
+		}
+	}
+	return nil, 0, false
+}
+
+// hover returns the markdown describing what's at the position
+func (doc *ssDocument) hover(pos lspPosition) (text string, found bool) {
+	line := doc.line(pos.Line)
+	if strings.HasPrefix(line, commentPrefix) {
+		return "", false
+	}
+	col := utf16ToColumn(line, pos.Character)
+	elem, offset, found := doc.elemAt(pos.Line)
+	if !found {
+		return "", false
+	}
+
+	switch el := elem.(type) {
+	case sine:
+		switch offset {
+		case 0:
+			for _, sa := range el.alongAxis {
+				start := int(sa.position * charsToaHump)
+				switch {
+				case sa.isMelody && col >= start && col <= start+1:
+					return doc.melodyHover(sa.mel), true
+				case sa.isChord() && col >= start && col < start+displayWidth(sa.chordName()):
+					return chordHover(sa.chordName(), nil), true
+				}
+			}
+		case 1, 2, 3:
+			pt, found := doc.timeline.playbackTimeAtCursor(col+1, pos.Line+1)
+			if found {
+				return fmt.Sprintf("playback time `%v`", pt.str), true
+			}
+		}
+
+	case melodies:
+		// the melodies are indexed by the display column
+		if offset <= 2 && col < len(el) && el[col].num != ' ' {
+			return doc.melodyHover(el[col]), true
+		}
+
+	case chordChart:
+		if offset > 8 || col < 2 {
+			return "", false
+		}
+		i := (col - 2) / 3
+		if i < len(el.chords) {
+			return chordHover(el.chords[i].name, el.chords[i].positions), true
+		}
+	}
+	return "", false
+}
+
+// melodyHover describes the melody number, along with the note when the
+// key of the song is known
+func (doc *ssDocument) melodyHover(m melody) string {
+	str := m.melodyString()
+	text := fmt.Sprintf("melody **%c** on the %v string (%v)",
+		m.num, ordinal(len(openStringNotes)-str), noteName(openStringNotes[str]))
+	if doc.keyFound {
+		if note, ok := m.midiNote(doc.key); ok {
+			text += fmt.Sprintf("\n\nnote %v%v", noteName(note), note/12-1)
+		}
+	}
+	return text
+}
+
+// chordHover describes the chord along with the fret positions if provided
+func chordHover(name string, positions []string) string {
+	text := fmt.Sprintf("chord **%v**", name)
+	if notes, ok := chordNotes(name); ok {
+		names := []string{}
+		for _, note := range notes {
+			names = append(names, noteName(note))
+		}
+		text += ": " + strings.Join(names, " ")
+	}
+	if len(positions) > 0 {
+		frets := []string{}
+		for _, p := range positions {
+			p = strings.TrimSpace(p)
+			if p == "" {
+				p = "x"
+			}
+			frets = append(frets, p)
+		}
+		text += fmt.Sprintf("\n\nfrets (thick to thin) `%v`", strings.Join(frets, " "))
+	}
+	return text
+}
+
+var sharpNoteNames = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// noteName returns the name of the midi note without its octave
+func noteName(note int) string {
+	return sharpNoteNames[(note%12+12)%12]
+}
+
+func ordinal(n int) string {
+	switch n {
+	case 1:
+		return "1st"
+	case 2:
+		return "2nd"
+	case 3:
+		return "3rd"
+	}
+	return fmt.Sprintf("%vth", n)
+}
+
+// format returns the formatted text of the document, trailing whitespace is
+// trimmed and the document ends with a single newline
+func (doc *ssDocument) format() string {
+	out := make([]string, len(doc.lines))
+	for i, line := range doc.lines {
+		out[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+	}
+	return strings.TrimRight(strings.Join(out, "\n"), "\n") + "\n"
+}
+
+// fullRange returns the range covering the whole document
+func (doc *ssDocument) fullRange() lspRange {
+	last := len(doc.lines) - 1
+	return lspRange{
+		Start: lspPosition{0, 0},
+		End:   lspPosition{last, utf16Len(doc.lines[last])},
+	}
+}
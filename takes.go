@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// A songsheet may link several audio takes (demo, rehearsal, live, etc.)
+// each through its own directive:
+//   // AUDIO-ID=12         the unlabelled take
+//   // AUDIO-ID-LIVE=13    the take labelled live
+//   // AUDIO-TAKE=live     (optional) the default take
+// Each take may have its own playback times under the sines, the
+// playback time lines of a labelled take end with @label.
+
+const (
+	audioDirectiveKey       = "AUDIO-ID"
+	defaultTakeDirectiveKey = "AUDIO-TAKE"
+	takeLabelPrefix         = "@"
+)
+
+var takeFlag string
+
+// registerTakeFlag adds the --take flag to the command
+func registerTakeFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(
+		&takeFlag, "take", "",
+		"label of the audio take to use (default the AUDIO-TAKE directive, or the unlabelled take)")
+}
+
+// songTake returns the label of the take selected by the flag, or else the
+// default take of the lines, whose playback times are to be parsed
+func songTake(lines []string) (string, error) {
+	if takeFlag != "" {
+		label := strings.ToLower(takeFlag)
+		if !isTakeLabel(label) {
+			return "", fmt.Errorf("bad --take %q, a label may only hold a-z, 0-9, and -", takeFlag)
+		}
+		return label, nil
+	}
+	label := defaultTake(lines)
+	if !isTakeLabel(label) {
+		return "", fmt.Errorf("bad %v %q, a label may only hold a-z, 0-9, and -",
+			defaultTakeDirectiveKey, label)
+	}
+	return label, nil
+}
+
+// contentTake returns the take of the songsheet content as per songTake
+func contentTake(content []byte) (string, error) {
+	return songTake(strings.Split(string(content), "\n"))
+}
+
+// isTakeLabel returns whether the label may be part of a directive key and
+// follow the @ of a playback time line, the unlabelled take is empty
+func isTakeLabel(label string) bool {
+	for _, r := range label {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultTake returns the label of the default take of the lines
+func defaultTake(lines []string) string {
+	label, _ := getDirective(lines, defaultTakeDirectiveKey)
+	return strings.ToLower(label)
+}
+
+// audioTake is an audio recording of the song
+type audioTake struct {
+	label string // empty for the unlabelled take
+	quid  uint32
+}
+
+// takeDirectiveKey returns the directive key linking the audio of the take
+func takeDirectiveKey(label string) string {
+	if label == "" {
+		return audioDirectiveKey
+	}
+	return audioDirectiveKey + "-" + strings.ToUpper(label)
+}
+
+// songsheetTakes returns all of the audio takes linked by the lines
+func songsheetTakes(lines []string) (takes []audioTake, err error) {
+	for _, line := range lines {
+		key, value, ok := parseDirective(line)
+		if !ok || !strings.HasPrefix(key, audioDirectiveKey) {
+			continue
+		}
+		label := ""
+		if key != audioDirectiveKey {
+			if !strings.HasPrefix(key, audioDirectiveKey+"-") {
+				continue
+			}
+			label = strings.ToLower(strings.TrimPrefix(key, audioDirectiveKey+"-"))
+		}
+		quid, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("bad %v: %v", key, err)
+		}
+		takes = append(takes, audioTake{label, uint32(quid)})
+	}
+	return takes, nil
+}
+
+// getTake returns the audio take with the label
+func getTake(lines []string, label string) (take audioTake, found bool, err error) {
+	takes, err := songsheetTakes(lines)
+	if err != nil {
+		return take, false, err
+	}
+	for _, take := range takes {
+		if take.label == label {
+			return take, true, nil
+		}
+	}
+	return take, false, nil
+}
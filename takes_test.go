@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTakeDirectiveRoundTrip(t *testing.T) {
+	for _, label := range []string{"", "live", "take-2", "v12"} {
+		lines := setDirective(strings.Split(testHeader, "\n"), takeDirectiveKey(label), "7")
+		take, found, err := getTake(lines, label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found || take.quid != 7 {
+			t.Errorf("take %q: got %+v (found %v), want quid 7\n%v",
+				label, take, found, strings.Join(lines, "\n"))
+		}
+	}
+}
+
+func TestSongTakeLabels(t *testing.T) {
+	defer func() { takeFlag = "" }()
+	lines := strings.Split(testHeader, "\n")
+
+	for _, flag := range []string{"my_take", "v1.2", "my take"} {
+		takeFlag = flag
+		if _, err := songTake(lines); err == nil {
+			t.Errorf("--take %q: expected an error", flag)
+		}
+	}
+
+	takeFlag = "Live"
+	if take, err := songTake(lines); err != nil || take != "live" {
+		t.Errorf("--take Live: got %q (%v), want live", take, err)
+	}
+
+	takeFlag = ""
+	if _, err := songTake(setDirective(lines, defaultTakeDirectiveKey, "my_take")); err == nil {
+		t.Errorf("%v=my_take: expected an error", defaultTakeDirectiveKey)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import "math"
+
+const (
+	// relative tempo difference below which two spans are the same tempo
+	tempoTolerance = 0.06
+
+	// minimum number of consecutive unsteady spans which are rubato
+	// rather than a tempo change
+	rubatoMinSpans = 3
+)
+
+// tempoSection is a section of the song between playback times which is
+// either at a steady tempo or in rubato
+type tempoSection struct {
+	start, end playbackMarker
+	spans      int // number of spans between playback times
+	rubato     bool
+}
+
+func (ts tempoSection) humps() float64 {
+	return ts.end.humps - ts.start.humps
+}
+
+func (ts tempoSection) minutes() float64 {
+	return ts.end.pt.t.Sub(ts.start.pt.t).Minutes()
+}
+
+// bpm returns the average bpm of the section
+func (ts tempoSection) bpm() float64 {
+	return ts.humps() / ts.minutes()
+}
+
+// tempoMap computes the bpm between every pair of playback times, grouping
+// them into sections of steady tempo. Runs of short sections which each
+// differ in tempo are grouped together as rubato.
+func tempoMap(markers []playbackMarker) (sections []tempoSection) {
+
+	// group the spans into sections of steady tempo
+	steady := []tempoSection{}
+	for i := 1; i < len(markers); i++ {
+		span := tempoSection{start: markers[i-1], end: markers[i], spans: 1}
+		if span.humps() <= 0 || span.minutes() <= 0 {
+			continue // out of order playback times can't be timed
+		}
+		if n := len(steady); n > 0 {
+			last := steady[n-1]
+			if last.end == span.start && sameTempo(last.bpm(), span.bpm()) {
+				steady[n-1].end = span.end
+				steady[n-1].spans++
+				continue
+			}
+		}
+		steady = append(steady, span)
+	}
+
+	// merge the runs of single span sections into rubato
+	for i := 0; i < len(steady); {
+		j := i
+		for j < len(steady) && steady[j].spans == 1 {
+			j++
+		}
+		if j-i >= rubatoMinSpans {
+			sections = append(sections, tempoSection{
+				start:  steady[i].start,
+				end:    steady[j-1].end,
+				spans:  j - i,
+				rubato: true,
+			})
+			i = j
+			continue
+		}
+		sections = append(sections, steady[i])
+		i++
+	}
+	return sections
+}
+
+func sameTempo(bpm1, bpm2 float64) bool {
+	return math.Abs(bpm1-bpm2)/math.Max(bpm1, bpm2) <= tempoTolerance
+}
+
+// mainBPM returns the bpm of the steady section which takes up the most humps,
+// or the average over all the sections if there are no steady sections
+func mainBPM(sections []tempoSection) (bpm float64, found bool) {
+	longest := 0.0
+	for _, sec := range sections {
+		if !sec.rubato && sec.humps() > longest {
+			bpm, longest, found = sec.bpm(), sec.humps(), true
+		}
+	}
+	if found || len(sections) == 0 {
+		return bpm, found
+	}
+	whole := tempoSection{start: sections[0].start, end: sections[len(sections)-1].end}
+	return whole.bpm(), true
+}
+
+// normalizeBPM folds the bpm into the range of 50 to 200 returning the factor
+// used, half-time or double-time feels are otherwise counted at the wrong
+// number of humps to a beat
+func normalizeBPM(bpm float64) (normalized, factor float64) {
+	factor = 1
+	if bpm <= 0 {
+		return bpm, factor
+	}
+	for bpm*factor < 50 {
+		factor *= 2
+	}
+	for bpm*factor > 200 {
+		factor /= 2
+	}
+	return bpm * factor, factor
+}
+
+type tempoMarkKind int
+
+const (
+	tempoRitardando tempoMarkKind = iota
+	tempoAccelerando
+	tempoATempo
+)
+
+// tempoMark is a tempo marking written along the axis of a sine
+type tempoMark struct {
+	position float64 // in humps
+	kind     tempoMarkKind
+}
+
+var tempoMarkWords = map[string]tempoMarkKind{
+	"rit":     tempoRitardando,
+	"rit.":    tempoRitardando,
+	"rall.":   tempoRitardando,
+	"accel":   tempoAccelerando,
+	"accel.":  tempoAccelerando,
+	"a tempo": tempoATempo,
+}
+
+// parseTempoMarks finds the tempo marking words (separated by spaces)
+// within the along axis line of a sine
+func parseTempoMarks(line string) (marks []tempoMark) {
+	cols := columnRunes(line)
+	for pos := range cols {
+		if pos > 0 && cols[pos-1] != ' ' {
+			continue
+		}
+		for word, kind := range tempoMarkWords {
+			end := pos + len([]rune(word))
+			if end > len(cols) || string(cols[pos:end]) != word {
+				continue
+			}
+			if end < len(cols) && cols[end] != ' ' {
+				continue
+			}
+			marks = append(marks, tempoMark{float64(pos) / charsToaHump, kind})
+		}
+	}
+	return marks
+}
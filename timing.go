@@ -0,0 +1,285 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultBPM = 120.0
+
+// humpTimer converts positions within the song (in humps from the start of
+// the song) into playback durations, interpolating between the playback times
+type humpTimer struct {
+	markers []playbackMarker
+	bpm     float64 // header bpm, used when there are not enough playback times
+}
+
+// headerBPM returns the bpm of the header or the default bpm if it's missing
+func headerBPM(hc headerContentFilled) float64 {
+	bpm, err := strconv.ParseFloat(strings.TrimSpace(hc.bpm), 64)
+	if err != nil || bpm <= 0 {
+		return defaultBPM
+	}
+	return bpm
+}
+
+// linesBPM returns the header bpm of the lines (comments already deleted),
+// or the default bpm if there's no header
+func linesBPM(lines []string) float64 {
+	_, hc, err := parseHeader(lines)
+	if err != nil {
+		return defaultBPM
+	}
+	return headerBPM(hc)
+}
+
+// at returns the playback duration at the position, positions beyond the
+// first or last playback times are extrapolated from the nearest pair
+func (ht humpTimer) at(humps float64) time.Duration {
+	switch len(ht.markers) {
+	case 0:
+		return humpsDuration(humps, ht.bpm)
+	case 1:
+		m := ht.markers[0]
+		return m.pt.elapsed() + humpsDuration(humps-m.humps, ht.bpm)
+	}
+
+	// find the pair of markers surrounding the position
+	i := 0
+	for ; i < len(ht.markers)-2; i++ {
+		if humps < ht.markers[i+1].humps {
+			break
+		}
+	}
+	first, last := ht.markers[i], ht.markers[i+1]
+	if last.humps <= first.humps {
+		return first.pt.elapsed() + humpsDuration(humps-first.humps, ht.bpm)
+	}
+	durPerHump := float64(last.pt.t.Sub(first.pt.t)) / (last.humps - first.humps)
+	return first.pt.elapsed() + time.Duration((humps-first.humps)*durPerHump)
+}
+
+// elapsed returns the duration of the playback time from the start of the song
+func (pt playbackTime) elapsed() time.Duration {
+	return pt.t.Sub(time.Time{})
+}
+
+// charTimer returns the timer of the characters along the text-sine-curves,
+// where each character is a quarter hump
+func charTimer(charPoss []charPos, bpm float64) humpTimer {
+	ht := humpTimer{bpm: bpm}
+	for i, cp := range charPoss {
+		if cp.hasPT {
+			ht.markers = append(ht.markers, playbackMarker{float64(i) / charsToaHump, cp.pt})
+		}
+	}
+	return ht
+}
+
+const (
+	// relative duration of a character by the end of a rit.
+	// (or the inverse for accel.)
+	ritardandoFactor = 1.5
+
+	// along sine character which marks a rest lasting until the
+	// next along sine character (or the end of the humps)
+	restMark = 'r'
+)
+
+// charWeight is the nominal duration of a character along the
+// text-sine-curves relative to a character at a steady tempo
+type charWeight struct {
+	weight float64
+	hold   bool // trailing humps and rests are held as long as needed
+}
+
+// charWeights returns the weight of every character of the sasses, tempo
+// marks ramp the weight up (rit.) or down (accel.) until the end of the humps
+// of the sine after which the new tempo is kept until an "a tempo"
+func (ls lineAndSasses) charWeights() (cws []charWeight) {
+	factor := 1.0
+	for _, s := range ls {
+		sas := s.sas
+		humpChars := int((sas.humps * charsToaHump) + 0.00001) // float rounding
+		maxChars := int((sas.totalHumps() * charsToaHump) + 0.00001)
+		rests := sas.restChars(humpChars)
+
+		rampStart, rampFrom, rampTo := 0, factor, factor
+		for j := 0; j < maxChars; j++ {
+			for _, tm := range sas.tempoMarks {
+				if int(tm.position*charsToaHump) != j {
+					continue
+				}
+				switch tm.kind {
+				case tempoRitardando:
+					rampStart, rampFrom, rampTo = j, factor, factor*ritardandoFactor
+				case tempoAccelerando:
+					rampStart, rampFrom, rampTo = j, factor, factor/ritardandoFactor
+				case tempoATempo:
+					rampFrom, rampTo, factor = 1, 1, 1
+				}
+			}
+			if rampFrom != rampTo && j < humpChars {
+				progress := float64(j-rampStart+1) / float64(humpChars-rampStart)
+				factor = rampFrom + (rampTo-rampFrom)*progress
+			}
+			cws = append(cws, charWeight{factor, j >= humpChars || rests[j]})
+		}
+	}
+	return cws
+}
+
+// restChars returns which characters of the humps are rests
+func (sas sine) restChars(humpChars int) map[int]bool {
+	rests := make(map[int]bool)
+	for i, as := range sas.alongSine {
+		if as.ch != restMark {
+			continue
+		}
+		end := humpChars
+		if i+1 < len(sas.alongSine) {
+			end = int(sas.alongSine[i+1].position * charsToaHump)
+		}
+		for j := int(as.position * charsToaHump); j < end; j++ {
+			rests[j] = true
+		}
+	}
+	return rests
+}
+
+// charTimeline is the playback duration at the start of every character
+// along the text-sine-curves, along with the end of the final character
+type charTimeline []time.Duration
+
+// newCharTimeline times the characters between each pair of playback times
+// by their weights. Where the characters between a pair include holds, the
+// other characters are kept at the tempo of the neighbouring steady span
+// while the holds take up the rest of the time. Characters before the first
+// or after the last playback time are extrapolated from the tempo of the
+// nearest span (or from the bpm), anchored is false if there are no playback
+// times at all in which case the first character is at zero.
+func newCharTimeline(cws []charWeight, charPoss []charPos, bpm float64) (
+	tl charTimeline, anchored bool) {
+
+	tl = make(charTimeline, len(cws)+1)
+	nominal := float64(time.Minute) / bpm / charsToaHump // per weight
+
+	markers := []int{}
+	for i, cp := range charPoss {
+		if cp.hasPT {
+			markers = append(markers, i)
+		}
+	}
+	if len(markers) == 0 {
+		tl.fill(cws, 0, 0, len(cws), nominal, nominal)
+		return tl, false
+	}
+
+	// the duration per weight of the spans without any holds
+	steadyUnits := make([]float64, len(markers)-1)
+	for k := range steadyUnits {
+		a, b := markers[k], markers[k+1]
+		w, wh := spanWeights(cws, a, b)
+		if wh == 0 && w > 0 {
+			steadyUnits[k] = float64(charPoss[b].pt.t.Sub(charPoss[a].pt.t)) / w
+		}
+	}
+	nearestSteadyUnit := func(k int) float64 {
+		for dist := 1; dist < len(steadyUnits); dist++ {
+			if k-dist >= 0 && steadyUnits[k-dist] > 0 {
+				return steadyUnits[k-dist]
+			}
+			if k+dist < len(steadyUnits) && steadyUnits[k+dist] > 0 {
+				return steadyUnits[k+dist]
+			}
+		}
+		return nominal
+	}
+
+	firstUnit, lastUnit := nominal, nominal
+	for k := range steadyUnits {
+		a, b := markers[k], markers[k+1]
+		w, wh := spanWeights(cws, a, b)
+		dur := float64(charPoss[b].pt.t.Sub(charPoss[a].pt.t))
+		unit, holdUnit := dur/w, dur/w
+		if wh > 0 && wh < w {
+			if steady := nearestSteadyUnit(k); (w-wh)*steady < dur {
+				unit, holdUnit = steady, (dur-(w-wh)*steady)/wh
+			}
+		}
+		tl.fill(cws, charPoss[a].pt.elapsed(), a, b, unit, holdUnit)
+		if k == 0 {
+			firstUnit = unit
+		}
+		lastUnit = unit
+	}
+
+	// extrapolate beyond the first and last playback times
+	first, last := markers[0], markers[len(markers)-1]
+	tl[first] = charPoss[first].pt.elapsed()
+	for i := first - 1; i >= 0; i-- {
+		tl[i] = tl[i+1] - time.Duration(cws[i].weight*firstUnit)
+	}
+	tl.fill(cws, charPoss[last].pt.elapsed(), last, len(cws), lastUnit, lastUnit)
+	return tl, true
+}
+
+// spanWeights returns the total weight of the characters from a up to b
+// along with the weight of those which are holds
+func spanWeights(cws []charWeight, a, b int) (w, wh float64) {
+	for _, cw := range cws[a:b] {
+		w += cw.weight
+		if cw.hold {
+			wh += cw.weight
+		}
+	}
+	return w, wh
+}
+
+// fill times the characters from a up to b starting at the duration
+func (tl charTimeline) fill(cws []charWeight, start time.Duration, a, b int,
+	unit, holdUnit float64) {
+
+	elapsed := float64(start)
+	for i := a; i < b; i++ {
+		tl[i] = time.Duration(elapsed)
+		if cws[i].hold {
+			elapsed += cws[i].weight * holdUnit
+		} else {
+			elapsed += cws[i].weight * unit
+		}
+	}
+	tl[b] = time.Duration(elapsed)
+}
+
+// songTimeline is the timing of every character along the text-sine-curves
+// of the lines of a songsheet file, for looking up the playback time of
+// positions within the file and back
+type songTimeline struct {
+	lasses      lineAndSasses // line numbers are of the lines without comments
+	charPoss    []charPos
+	tl          charTimeline
+	anchored    bool
+	origIndexes []int // original index of each line without comments
+}
+
+// newSongTimeline times the lines of the songsheet file (including comments)
+// by the playback times of the take
+func newSongTimeline(lines []string, take string) songTimeline {
+	lines, origIndexes := deleteCommentsMapped(lines)
+	lasses := getLasses(lines, take)
+	charPoss := lasses.charPositions()
+	tl, anchored := newCharTimeline(lasses.charWeights(), charPoss, linesBPM(lines))
+	return songTimeline{lasses, charPoss, tl, anchored, origIndexes}
+}
+
+// charAt returns the index of the character playing at the duration
+func (tl charTimeline) charAt(d time.Duration) (i int, found bool) {
+	for i := 0; i+1 < len(tl); i++ {
+		if tl[i] <= d && d < tl[i+1] {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCharTimeline(t *testing.T) {
+	// the rit. weights ramp 1.0625, 1.125 ... 1.5 totalling 10.25 over 4s
+	ritUnit := float64(4*time.Second) / 10.25
+
+	tests := []struct {
+		name string
+		body string
+		want map[int]time.Duration // by character index along the sines
+	}{
+		{
+			name: "steady",
+			body: `G
+_   _
+ \_/ \_/
+
+00:00.00
+G
+_   _
+ \_/ \_/
+
+00:04.00`,
+			want: map[int]time.Duration{
+				4:  2 * time.Second,
+				8:  4 * time.Second,
+				12: 6 * time.Second, // extrapolated
+			},
+		},
+		{
+			name: "rit. slows through the humps of the sine",
+			body: `rit.
+_   _
+ \_/ \_/
+
+00:00.00
+G
+_   _
+ \_/ \_/
+
+00:04.00`,
+			want: map[int]time.Duration{
+				1: time.Duration(1.0625 * ritUnit),
+				7: time.Duration((10.25 - 1.5) * ritUnit),
+				8: 4 * time.Second,
+				9: 4*time.Second + time.Duration(1.5*ritUnit), // the slower tempo is kept
+			},
+		},
+		{
+			name: "trailing hump held",
+			body: `G
+_   _
+ \_/ \_/
+
+00:00.00
+G
+_   _
+ \_/ \_/....
+
+00:04.00
+G
+_
+ \_/
+
+00:12.00`,
+			want: map[int]time.Duration{
+				12: 6 * time.Second, // the humps at the steady tempo
+				16: 8 * time.Second, // the hold takes the rest
+				18: 10 * time.Second,
+				20: 12 * time.Second,
+			},
+		},
+		{
+			name: "rest held",
+			body: `G
+_   _
+ \_/ \_/
+
+00:00.00
+G
+_   _
+ \_/ \_/
+    r
+00:04.00
+G
+_
+ \_/
+
+00:12.00`,
+			want: map[int]time.Duration{
+				12: 6 * time.Second, // before the rest at the steady tempo
+				14: 9 * time.Second, // the rest takes the rest
+				16: 12 * time.Second,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			lines := strings.Split(tc.body, "\n")
+			lasses := getLasses(lines, "")
+			charPoss := lasses.charPositions()
+			tl, anchored := newCharTimeline(lasses.charWeights(), charPoss, defaultBPM)
+			if !anchored {
+				t.Fatal("not anchored")
+			}
+			for i, want := range tc.want {
+				if diff := tl[i] - want; diff > time.Millisecond || diff < -time.Millisecond {
+					t.Errorf("character %v: got %v, want %v", i, tl[i], want)
+				}
+			}
+		})
+	}
+}
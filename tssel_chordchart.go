@@ -58,8 +58,12 @@ func (c chordChart) parseText(lines []string) (reduced []string, elem tssElement
 		labelFontPt:     12,
 		positionsFontPt: 10,
 	}
-	// get the chords
-	chordNames := lines[8]
+	// get the chords, indexing every line by display column
+	chordNames := columnRunes(lines[8])
+	positionLines := make([][]rune, 7)
+	for i := 1; i <= 6; i++ {
+		positionLines[i] = columnRunes(lines[i])
+	}
 	for j := 2; j < len(chordNames); j += 3 {
 
 		if chordNames[j] == ' ' {
@@ -79,11 +83,14 @@ func (c chordChart) parseText(lines []string) (reduced []string, elem tssElement
 
 		// add all the guitar strings
 		for i := 1; i <= 6; i++ {
-			word := string(lines[i][j])
+			word := " "
+			if j < len(positionLines[i]) {
+				word = string(positionLines[i][j])
+			}
 
-			if j+1 < len(lines[i]) {
-				if lines[i][j+1] != ' ' {
-					word += string(lines[i][j+1])
+			if j+1 < len(positionLines[i]) {
+				if positionLines[i][j+1] != ' ' {
+					word += string(positionLines[i][j+1])
 				}
 			}
 			newChord.positions = append(newChord.positions, word)
@@ -207,7 +214,7 @@ func (c chordChart) printPDF(pdf Pdf, bnd bounds) (reduced bounds) {
 	xStart = bnd.left + thicknessIndicatorMargin + cactusPrickleSpacing/2
 	xEnd = bnd.right - padding
 	chordIndex := 0
-	pdf.SetFont("courier", "", c.labelFontPt)
+	pdf.SetFont(fontFamily, "", c.labelFontPt)
 	fontHeight := GetFontHeight(c.labelFontPt)
 	labelPadding := fontHeight * 0.1
 	fontWidth := GetCourierFontWidthFromHeight(fontHeight)
@@ -229,15 +236,16 @@ func (c chordChart) printPDF(pdf Pdf, bnd bounds) (reduced bounds) {
 		chd := c.chords[chordIndex]
 
 		ch1, ch2, ch3 := ' ', ' ', ' '
-		switch len(chd.name) {
+		name := []rune(chd.name)
+		switch len(name) {
 		case 3:
-			ch3 = rune(chd.name[2])
+			ch3 = name[2]
 			fallthrough
 		case 2:
-			ch2 = rune(chd.name[1])
+			ch2 = name[1]
 			fallthrough
 		case 1:
-			ch1 = rune(chd.name[0])
+			ch1 = name[0]
 		}
 
 		subscriptCh, superscriptCh, _ := determineChordsSubscriptSuperscriptSlide(
@@ -245,19 +253,19 @@ func (c chordChart) printPDF(pdf Pdf, bnd bounds) (reduced bounds) {
 
 		xLabel := x - fontWidth/2
 		yLabel := yBottomEnd + fontHeight + labelPadding
-		pdf.SetFont("courier", "", c.labelFontPt)
-		pdf.Text(xLabel, yLabel, string(ch1))
+		pdf.SetFont(fontFamily, "", c.labelFontPt)
+		pdf.Text(xLabel, yLabel, encodeText(string(ch1)))
 
 		if subscriptCh != ' ' {
-			pdf.SetFont("courier", "", c.labelFontPt*subsupSizeMul)
-			pdf.Text(xLabel+fontWidth, yLabel, string(subscriptCh))
+			pdf.SetFont(fontFamily, "", c.labelFontPt*subsupSizeMul)
+			pdf.Text(xLabel+fontWidth, yLabel, encodeText(string(subscriptCh)))
 		}
 		if superscriptCh != ' ' {
 			panic("chords labels cannot have superscript")
 		}
 
 		// print positions
-		pdf.SetFont("courier", "", c.positionsFontPt)
+		pdf.SetFont(fontFamily, "", c.positionsFontPt)
 		posFontH := GetFontHeight(c.positionsFontPt)
 		posFontW := GetCourierFontWidthFromHeight(posFontH)
 		//xPositions := x - fontWidth/2 // maybe incorrect, but looks better
@@ -274,7 +282,7 @@ func (c chordChart) printPDF(pdf Pdf, bnd bounds) (reduced bounds) {
 				continue
 			}
 
-			pdf.Text(xPositions, yPositions, chd.positions[i])
+			pdf.Text(xPositions, yPositions, encodeText(chd.positions[i]))
 		}
 
 		chordIndex++
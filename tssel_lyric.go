@@ -30,7 +30,7 @@ func (s lyrics) printPDF(pdf Pdf, bnd bounds) (reduced bounds) {
 	usedHeight := 0.0
 
 	// print the lyric
-	pdf.SetFont("courier", "", lyricFontPt)
+	pdf.SetFont(fontFamily, "", lyricFontPt)
 	fontH := GetFontHeight(lyricFontPt)
 	fontW := GetCourierFontWidthFromHeight(fontH)
 	xLyricStart := bnd.left - fontW/2 // - because of slight right shift in sine annotations
@@ -42,9 +42,13 @@ func (s lyrics) printPDF(pdf Pdf, bnd bounds) (reduced bounds) {
 	// however do to the inaccuracies of determining
 	// font heights and widths (boohoo) it will look
 	// better to just print out each char individually
-	for i, ch := range s.lyrics {
+	// (at its display column so it lines up with the sine above)
+	for i, col := range splitColumns(s.lyrics) {
+		if col == "" {
+			continue // second half of a wide character
+		}
 		xLyric := xLyricStart + float64(i)*fontW
-		pdf.Text(xLyric, yLyric, string(ch))
+		pdf.Text(xLyric, yLyric, encodeText(col))
 	}
 	usedHeight += 1.3 * fontH
 	return bounds{bnd.top + usedHeight, bnd.left, bnd.bottom, bnd.right}
@@ -59,10 +63,10 @@ func determineLyricFontPt(
 		if strings.HasPrefix(lines[i], "_") &&
 			strings.HasPrefix(lines[i+1], " \\_/") {
 
-			humpsCharsNew := len(strings.TrimSpace(lines[i]))
+			humpsCharsNew := displayWidth(strings.TrimSpace(lines[i]))
 
 			// +1 for the leading space just trimmed
-			secondLineLen := len(strings.TrimSpace(lines[i+1])) + 1
+			secondLineLen := displayWidth(strings.TrimSpace(lines[i+1])) + 1
 
 			if humpsCharsNew < secondLineLen {
 				humpsCharsNew = secondLineLen
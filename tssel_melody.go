@@ -73,13 +73,13 @@ func (m melody) print(pdf Pdf, x, y float64, fontH float64, nextMelodyNum rune,
 
 	// print the melodies
 	melodyFontPt := lyricFontPt
-	pdf.SetFont("courier", "", melodyFontPt)
+	pdf.SetFont(fontFamily, "", melodyFontPt)
 	melodyFontH := GetFontHeight(melodyFontPt)
 	melodyFontW := GetCourierFontWidthFromHeight(melodyFontH)
 	melodyHPadding := melodyFontH * 0.3
 
 	// print number
-	pdf.Text(x, y, string(m.num))
+	pdf.Text(x, y, encodeText(string(m.num)))
 
 	// print modifier
 	switch m.modifier {
@@ -402,9 +402,13 @@ func (ms melodies) parseText(lines []string) (reduced []string, elem tssElement,
 		return lines, elem, fmt.Errorf("could not determine melody number line and modifier line")
 	}
 
+	// index all three lines by display column so the
+	// modifiers line up with the numbers they sit above or below
+	upperCols, lowerCols := columnRunes(upper), columnRunes(lower)
+
 	var msOut melodies
 	melodiesFound := false
-	for i, r := range melodyNums {
+	for i, r := range columnRunes(melodyNums) {
 		if !(unicode.IsSpace(r) || unicode.IsNumber(r)) {
 			return lines, elem, fmt.Errorf(
 				"melodies line contains something other"+
@@ -417,11 +421,11 @@ func (ms melodies) parseText(lines []string) (reduced []string, elem tssElement,
 
 		m := melody{num: r, modifier: ' ', extra: ' '}
 		chAbove, chBelow := ' ', ' '
-		if len(upper) > i && !unicode.IsSpace(rune(upper[i])) {
-			chAbove = rune(upper[i])
+		if len(upperCols) > i && !unicode.IsSpace(upperCols[i]) {
+			chAbove = upperCols[i]
 		}
-		if len(lower) > i && !unicode.IsSpace(rune(lower[i])) {
-			chBelow = rune(lower[i])
+		if len(lowerCols) > i && !unicode.IsSpace(lowerCols[i]) {
+			chBelow = lowerCols[i]
 		}
 		if runeIsExtra(chAbove) {
 			m.extra = chAbove
@@ -443,7 +447,7 @@ func (ms melodies) parseText(lines []string) (reduced []string, elem tssElement,
 		}
 		if !runeIsMod(m.modifier) {
 			return lines, elem, fmt.Errorf(
-				"bad modifier not '%s', '%s', or '%s' (have %v)",
+				"bad modifier not '%c', '%c', or '%c' (have %v)",
 				mod1, mod2, mod3, m.modifier)
 		}
 
@@ -455,6 +459,9 @@ func (ms melodies) parseText(lines []string) (reduced []string, elem tssElement,
 		return lines, elem, fmt.Errorf("no melodies found")
 	}
 
+	if len(lines) < 3 {
+		return nil, msOut, nil
+	}
 	return lines[3:], msOut, nil
 }
 
@@ -470,7 +477,7 @@ func (ms melodies) printPDF(pdf Pdf, bnd bounds) (reduced bounds) {
 
 	// print the melodies
 	melodyFontPt := lyricFontPt
-	pdf.SetFont("courier", "", melodyFontPt)
+	pdf.SetFont(fontFamily, "", melodyFontPt)
 	melodyFontH := GetFontHeight(melodyFontPt)
 	melodyHPadding := melodyFontH * 0.3
 	melodyWPadding := 0.0
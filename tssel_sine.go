@@ -19,13 +19,29 @@ import (
 //                                                                 / \_
 
 type sine struct {
-	hasPlaybackTime bool
-	pt              playbackTime
-	ptCharPosition  int // number of humps to the playback position
-	humps           float64
-	trailingHumps   float64 // the sine curve reduces its amplitude to zero during these
-	alongAxis       []sineAnnotation
-	alongSine       []sineAnnotation
+	playbackTimes []sinePlaybackTime // of the selected take, in order of position
+	ptLineOffset  int                // line offset of the playback times of the selected take
+	ptLines       int                // number of playback time lines (one per take)
+	humps         float64
+	trailingHumps float64 // the sine curve reduces its amplitude to zero during these
+	alongAxis     []sineAnnotation
+	alongSine     []sineAnnotation
+	tempoMarks    []tempoMark // rit., accel., and a tempo along the axis
+	waveform      []float64   // (optional) audio amplitude envelope, see addWaveforms
+
+	// label of the take whose playback times are parsed (empty for the
+	// unlabelled take), set on the element used to parse
+	take string
+}
+
+// sinePlaybackTime is one of the playback times written under a sine
+type sinePlaybackTime struct {
+	pt           playbackTime
+	charPosition int // number of characters along the sine to the playback time
+}
+
+func (sas sine) hasPlaybackTime() bool {
+	return len(sas.playbackTimes) > 0
 }
 
 func (sas sine) totalHumps() float64 {
@@ -56,7 +72,7 @@ func (sa sineAnnotation) printAlongAxis(pdf Pdf, x, y float64, fontH float64) {
 	if sa.bolded {
 		bolded = "B"
 	}
-	pdf.SetFont("courier", bolded, fontPt)
+	pdf.SetFont(fontFamily, bolded, fontPt)
 
 	if sa.isMelody {
 		x += fontW * 0.16 // weird corrections to make the
@@ -65,19 +81,19 @@ func (sa sineAnnotation) printAlongAxis(pdf Pdf, x, y float64, fontH float64) {
 		return
 	}
 
-	pdf.Text(x, y, string(sa.ch))
+	pdf.Text(x, y, encodeText(string(sa.ch)))
 
 	// print sub or super script if exists
 	if sa.subscript != ' ' || sa.superscript != ' ' {
 		Xsubsup := x + fontW - XsubsupCrunch
-		pdf.SetFont("courier", bolded, fontPtSubSup)
+		pdf.SetFont(fontFamily, bolded, fontPtSubSup)
 		if sa.subscript != ' ' {
 			Ysub := y - fontH/2 + fontHSubSup
-			pdf.Text(Xsubsup, Ysub, string(sa.subscript))
+			pdf.Text(Xsubsup, Ysub, encodeText(string(sa.subscript)))
 		}
 		if sa.superscript != ' ' {
 			Ysuper := y - fontH/2
-			pdf.Text(Xsubsup, Ysuper, string(sa.superscript))
+			pdf.Text(Xsubsup, Ysuper, encodeText(string(sa.superscript)))
 		}
 	}
 
@@ -98,7 +114,7 @@ func NewSineAnnotation(position float64, bolded bool,
 
 var _ tssElement = sine{}
 
-func GetSASFromTopLines(lines []string) (sas sine, err error) {
+func GetSASFromTopLines(lines []string, take string) (sas sine, err error) {
 
 	// the annotated sine must come in 4 OR 5 Lines
 	//    ex.   desciption
@@ -106,7 +122,8 @@ func GetSASFromTopLines(lines []string) (sas sine, err error) {
 	// 2) _   _   _   _  text representation of the sine humps (top)
 	// 3)  \_/ \_/ \_/   text representation of the sine humps (bottom)
 	// 4)   ^   ^ 1   v  annotations along the sine curve
-	// 5)     00:03.14   (optional) playback time position
+	// 5)     00:03.14   (optional) playback time positions
+	// 6)   00:04.10 @live  (optional) playback time positions of other takes
 
 	if len(lines) < 4 {
 		return sas, fmt.Errorf("improper number of input lines,"+
@@ -120,13 +137,16 @@ func GetSASFromTopLines(lines []string) (sas sine, err error) {
 		return sas, fmt.Errorf("first lines are not sine humps")
 	}
 
-	// get the playback time if it exists
-	if len(lines) > 4 {
-		pt, ptCharPosition, ptFound := getPlaybackTimeFromLine(lines[4])
-		sas = sine{
-			hasPlaybackTime: ptFound,
-			ptCharPosition:  ptCharPosition,
-			pt:              pt,
+	// get the playback times of the take if they exist
+	sas.take = take
+	for i := 4; i < len(lines); i++ {
+		ptTake, pts, found := getTakePlaybackTimesFromLine(lines[i])
+		if !found {
+			break
+		}
+		sas.ptLines++
+		if ptTake == take {
+			sas.playbackTimes, sas.ptLineOffset = pts, i
 		}
 	}
 
@@ -136,81 +156,204 @@ func GetSASFromTopLines(lines []string) (sas sine, err error) {
 type playbackTime struct {
 	// string representation
 	//   mn:se.cs
+	// or with hours and/or milliseconds
+	//   h:mn:se.cs
+	//   mn:se.mls
 	// where
-	//   mn = minutes
-	//   se = seconds
-	//   cs = centi-seconds (1/100th of a second)
-	str string // string representation
-	t   time.Time
+	//   h   = hours
+	//   mn  = minutes (may exceed 59 without hours)
+	//   se  = seconds
+	//   cs  = centi-seconds (1/100th of a second)
+	//   mls = milli-seconds (1/1000th of a second)
+	str    string // string representation
+	t      time.Time
+	millis bool // whether the string is to millisecond precision
 }
 
+// AddDur returns the playback time the duration later, formatted to the
+// same precision
 func (pt playbackTime) AddDur(d time.Duration) (ptOut playbackTime) {
 	ptOut.t = pt.t.Add(d)
-	newDur := ptOut.t.Sub(time.Time{})
-
-	minsFl := math.Trunc(newDur.Seconds() / 60)
-	minsStr := fmt.Sprintf("%02v", minsFl)
-	secsFl := newDur.Seconds() - 60*minsFl
-	secsStr := fmt.Sprintf("%05v", strconv.FormatFloat(secsFl, 'f', 2, 64))
-	ptOut.str = fmt.Sprintf("%v:%v", minsStr, secsStr) // secsStr contains two decimals
+	ptOut.millis = pt.millis
+	ptOut.str = formatPlaybackTime(ptOut.t.Sub(time.Time{}), pt.millis)
 	return ptOut
 }
 
-// 00:00.00
-func getPlaybackTimeFromLine(line string) (pt playbackTime, ptCharPosition int, found bool) {
-	tr := strings.TrimSpace(line)
-	if len(tr) != 8 {
-		return pt, 0, false
+// formatPlaybackTime formats the duration as mm:ss.cs (or mm:ss.mls),
+// durations of an hour or more are formatted as h:mm:ss.cs
+func formatPlaybackTime(d time.Duration, millis bool) string {
+	unit, fracFmt := 10*time.Millisecond, "%02d"
+	if millis {
+		unit, fracFmt = time.Millisecond, "%03d"
 	}
-	str := tr
-	spl1 := strings.SplitN(tr, ":", 2)
-	if len(spl1) != 2 {
-		return pt, 0, false
+	d = d.Round(unit)
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
 	}
-	spl2 := strings.SplitN(spl1[1], ".", 2)
-	if len(spl1) != 2 {
-		return pt, 0, false
+	hours := d / time.Hour
+	mins := (d % time.Hour) / time.Minute
+	secs := (d % time.Minute) / time.Second
+	frac := fmt.Sprintf(fracFmt, (d%time.Second)/unit)
+	if hours > 0 {
+		return fmt.Sprintf("%v%d:%02d:%02d.%v", sign, hours, mins, secs, frac)
 	}
+	return fmt.Sprintf("%v%02d:%02d.%v", sign, mins, secs, frac)
+}
 
-	mins, err := strconv.Atoi(spl1[0])
+// parsePlaybackTime parses a single playback time such as 00:03.14,
+// 1:02:03.14, or 00:03.141
+func parsePlaybackTime(str string) (pt playbackTime, found bool) {
+	spl1 := strings.Split(str, ":")
+	if len(spl1) != 2 && len(spl1) != 3 {
+		return pt, false
+	}
+	spl2 := strings.SplitN(spl1[len(spl1)-1], ".", 2)
+	if len(spl2) != 2 || len(spl2[0]) != 2 {
+		return pt, false
+	}
+
+	hours := 0
+	mins, err := parsePlaybackTimeField(spl1[len(spl1)-2], 1, -1)
 	if err != nil {
-		return pt, 0, false
+		return pt, false
 	}
-	secs, err := strconv.Atoi(spl2[0])
+	if len(spl1) == 3 {
+		hours, err = parsePlaybackTimeField(spl1[0], 1, -1)
+		if err != nil || len(spl1[1]) != 2 || mins > 59 {
+			return pt, false
+		}
+	}
+	secs, err := parsePlaybackTimeField(spl2[0], 2, 59)
 	if err != nil {
-		return pt, 0, false
+		return pt, false
 	}
-	centiSecs, err := strconv.Atoi(spl2[1])
+	frac, err := parsePlaybackTimeField(spl2[1], 2, -1)
 	if err != nil {
-		return pt, 0, false
+		return pt, false
 	}
 
 	// get the time in the golang time format
-	dur := time.Minute * time.Duration(mins)
+	dur := time.Hour * time.Duration(hours)
+	dur += time.Minute * time.Duration(mins)
 	dur += time.Second * time.Duration(secs)
-	dur += time.Millisecond * 10 * time.Duration(centiSecs)
-	t := time.Time{}.Add(dur)
+	switch len(spl2[1]) {
+	case 2:
+		dur += time.Millisecond * 10 * time.Duration(frac)
+	case 3:
+		dur += time.Millisecond * time.Duration(frac)
+		pt.millis = true
+	default:
+		return pt, false
+	}
+
+	pt.str = str
+	pt.t = time.Time{}.Add(dur)
+	return pt, true
+}
+
+// parsePlaybackTimeField parses a field of only digits, at least minDigits
+// long and no greater than max (unless max is negative)
+func parsePlaybackTimeField(str string, minDigits, max int) (int, error) {
+	if len(str) < minDigits {
+		return 0, fmt.Errorf("playback time field too short: %v", str)
+	}
+	for _, r := range str {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("playback time field not a number: %v", str)
+		}
+	}
+	n, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, err
+	}
+	if max >= 0 && n > max {
+		return 0, fmt.Errorf("playback time field too large: %v", str)
+	}
+	return n, nil
+}
 
-	pt = playbackTime{
-		str: str,
-		t:   t,
+// getPlaybackTimesFromLine parses a line of one or more playback times
+// separated by spaces, each positioned at its own display column
+func getPlaybackTimesFromLine(line string) (pts []sinePlaybackTime, found bool) {
+	col := 0
+	for _, field := range strings.SplitAfter(strings.TrimRight(line, " \t\r"), " ") {
+		str := strings.TrimRight(field, " ")
+		if str != "" {
+			pt, found := parsePlaybackTime(str)
+			if !found {
+				return nil, false
+			}
+			pts = append(pts, sinePlaybackTime{pt, col})
+		}
+		col += displayWidth(field)
 	}
+	return pts, len(pts) > 0
+}
 
-	ptCharPosition = len(line) - len(strings.TrimLeft(line, " "))
-	return pt, ptCharPosition, true
+// getTakePlaybackTimesFromLine parses a line of playback times which may
+// end with the label of the take they belong to (as @label)
+func getTakePlaybackTimesFromLine(line string) (take string, pts []sinePlaybackTime, found bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if i := strings.LastIndex(line, " "+takeLabelPrefix); i >= 0 {
+		take = strings.ToLower(line[i+1+len(takeLabelPrefix):])
+		if take == "" || strings.Contains(take, " ") {
+			return "", nil, false
+		}
+		line = line[:i]
+	}
+	pts, found = getPlaybackTimesFromLine(line)
+	return take, pts, found
+}
+
+// formatTakePlaybackTimesLine returns the line of playback times labelled
+// with the take (unless it's the unlabelled take)
+func formatTakePlaybackTimesLine(take string, pts []sinePlaybackTime) string {
+	line := formatPlaybackTimesLine(pts)
+	if take != "" {
+		line += " " + takeLabelPrefix + take
+	}
+	return line
+}
+
+// getPlaybackTimeFromLine parses a line holding a single playback time
+// (00:00.00) returning its position
+func getPlaybackTimeFromLine(line string) (pt playbackTime, ptCharPosition int, found bool) {
+	pts, found := getPlaybackTimesFromLine(line)
+	if !found || len(pts) != 1 {
+		return pt, 0, false
+	}
+	return pts[0].pt, pts[0].charPosition, true
+}
+
+// formatPlaybackTimesLine returns the line with each of the playback times
+// at its position, playback times which would overlap are spaced apart
+func formatPlaybackTimesLine(pts []sinePlaybackTime) string {
+	line := ""
+	for _, spt := range pts {
+		width := displayWidth(line)
+		switch {
+		case spt.charPosition > width:
+			line += strings.Repeat(" ", spt.charPosition-width)
+		case width > 0:
+			line += " "
+		}
+		line += spt.pt.str
+	}
+	return line
 }
 
 func (s sine) parseText(lines []string) (reduced []string, elem tssElement, err error) {
 
-	sas, err := GetSASFromTopLines(lines)
+	sas, err := GetSASFromTopLines(lines, s.take)
 	if err != nil {
 		return lines, elem, err
 	}
 
-	humpsChars := len(strings.TrimSpace(lines[1]))
+	humpsChars := displayWidth(strings.TrimSpace(lines[1]))
 	secondLineTrimTrail := strings.TrimRight(lines[2], ".")
 	// +1 for the leading space just trimmed
-	secondLineLen := len(strings.TrimSpace(secondLineTrimTrail)) + 1
+	secondLineLen := displayWidth(strings.TrimSpace(secondLineTrimTrail)) + 1
 	if humpsChars < secondLineLen {
 		humpsChars = secondLineLen
 	}
@@ -220,10 +363,11 @@ func (s sine) parseText(lines []string) (reduced []string, elem tssElement, err
 	trailingHumps := float64(trailingHumpsChars) / charsToaHump
 
 	// parse along axis text
+	// (positions are display columns so they line up with the humps)
 	alongAxis := []sineAnnotation{}
-	fl := lines[0]
+	fl := columnRunes(lines[0])
 	for pos := 0; pos < len(fl); pos++ {
-		ch := rune(fl[pos])
+		ch := fl[pos]
 		if ch == ' ' {
 			continue
 		}
@@ -234,13 +378,13 @@ func (s sine) parseText(lines []string) (reduced []string, elem tssElement, err
 		hasNextNextNextCh := pos+3 < len(fl)
 		nextCh, nextNextCh, nextNextNextCh := ' ', ' ', ' '
 		if hasNextCh {
-			nextCh = rune(fl[pos+1])
+			nextCh = fl[pos+1]
 		}
 		if hasNextNextCh {
-			nextNextCh = rune(fl[pos+2])
+			nextNextCh = fl[pos+2]
 		}
 		if hasNextNextNextCh {
-			nextNextNextCh = rune(fl[pos+3])
+			nextNextNextCh = fl[pos+3]
 		}
 
 		// check if it's a melody
@@ -282,7 +426,7 @@ func (s sine) parseText(lines []string) (reduced []string, elem tssElement, err
 
 	// parse along sine text
 	alongSine := []sineAnnotation{}
-	for pos, ch := range lines[3] {
+	for pos, ch := range columnRunes(lines[3]) {
 		if ch == ' ' {
 			continue
 		}
@@ -305,10 +449,8 @@ func (s sine) parseText(lines []string) (reduced []string, elem tssElement, err
 	sas.trailingHumps = trailingHumps
 	sas.alongAxis = alongAxis
 	sas.alongSine = alongSine
-	if sas.hasPlaybackTime {
-		return lines[5:], sas, nil
-	}
-	return lines[4:], sas, nil
+	sas.tempoMarks = parseTempoMarks(lines[0])
+	return lines[4+sas.ptLines:], sas, nil
 }
 
 func (s sine) printPDF(pdf Pdf, bnd bounds) (reduced bounds) {
@@ -338,6 +480,9 @@ func (s sine) printPDF(pdf Pdf, bnd bounds) (reduced bounds) {
 	yStart := bnd.top + usedHeight/2
 	lastPointX := xStart
 	lastPointY := yStart
+
+	// print the waveform of the recording behind the sine
+	s.printWaveform(pdf, xStart, yStart, width/s.humps, width+trailingWidth, amplitude)
 	pdf.SetLineWidth(thinestLW)
 
 	// regular sinepart
@@ -466,9 +611,9 @@ func (s sine) printPDF(pdf Pdf, bnd bounds) (reduced bounds) {
 			// 45deg angles to the tip
 			if as.bolded { // draw a closed polygon instead of just lines
 				pts := []gofpdf.PointType{
-					{tipX - chhbs, tipY - chhbs},
-					{tipX + chhbs, tipY - chhbs},
-					{tipX, tipY},
+					{X: tipX - chhbs, Y: tipY - chhbs},
+					{X: tipX + chhbs, Y: tipY - chhbs},
+					{X: tipX, Y: tipY},
 				}
 				pdf.Polygon(pts, "FD")
 			} else {
@@ -486,9 +631,9 @@ func (s sine) printPDF(pdf Pdf, bnd bounds) (reduced bounds) {
 
 			if as.bolded { // draw a closed polygon instead of just lines
 				pts := []gofpdf.PointType{
-					{tipX - chhbs, tipY + chhbs},
-					{tipX + chhbs, tipY + chhbs},
-					{tipX, tipY},
+					{X: tipX - chhbs, Y: tipY + chhbs},
+					{X: tipX + chhbs, Y: tipY + chhbs},
+					{X: tipX, Y: tipY},
 				}
 				pdf.Polygon(pts, "FD")
 			} else {
@@ -505,7 +650,7 @@ func (s sine) printPDF(pdf Pdf, bnd bounds) (reduced bounds) {
 			w := GetCourierFontWidthFromHeight(h) // font width
 
 			// we want the character to be centered about the sine curve
-			pdf.SetFont("courier", bolded, fontPt)
+			pdf.SetFont(fontFamily, bolded, fontPt)
 			tipX := xStart + eqX
 			tipY := yStart - eqY
 			shiftH := h / 2
@@ -520,7 +665,7 @@ func (s sine) printPDF(pdf Pdf, bnd bounds) (reduced bounds) {
 				shiftH -= h / 2
 			}
 
-			pdf.Text(tipX-(w/2), tipY+shiftH, string(as.ch))
+			pdf.Text(tipX-(w/2), tipY+shiftH, encodeText(string(as.ch)))
 		}
 	}
 
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// writeWAV encodes the mono samples (-1 to 1) as a 16-bit pcm wav file
+func writeWAV(samples []float64, sampleRate int) []byte {
+	return writeWAVChannels([][]float64{samples}, sampleRate)
+}
+
+// writeWAVChannels encodes the samples of each channel (-1 to 1, all of the
+// same length) as a 16-bit pcm wav file
+func writeWAVChannels(chans [][]float64, sampleRate int) []byte {
+	const bitsPerSample = 16
+	channels := len(chans)
+	dataLen := len(chans[0]) * bitsPerSample / 8 * channels
+
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, le, uint32(36+dataLen))
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	_ = binary.Write(&buf, le, uint32(16))
+	_ = binary.Write(&buf, le, uint16(1)) // pcm
+	_ = binary.Write(&buf, le, uint16(channels))
+	_ = binary.Write(&buf, le, uint32(sampleRate))
+	_ = binary.Write(&buf, le, uint32(sampleRate*channels*bitsPerSample/8)) // byte rate
+	_ = binary.Write(&buf, le, uint16(channels*bitsPerSample/8))            // block align
+	_ = binary.Write(&buf, le, uint16(bitsPerSample))
+
+	buf.WriteString("data")
+	_ = binary.Write(&buf, le, uint32(dataLen))
+	for i := range chans[0] {
+		for _, samples := range chans {
+			s := math.Max(-1, math.Min(1, samples[i]))
+			_ = binary.Write(&buf, le, int16(s*math.MaxInt16))
+		}
+	}
+	return buf.Bytes()
+}
+
+// decodeWAV decodes the wav file into mono samples (-1 to 1) by averaging
+// the channels
+func decodeWAV(content []byte) (samples []float64, sampleRate int, err error) {
+	chans, sampleRate, err := decodeWAVChannels(content)
+	if err != nil {
+		return nil, 0, err
+	}
+	samples = make([]float64, len(chans[0]))
+	for _, chSamples := range chans {
+		for i, s := range chSamples {
+			samples[i] += s / float64(len(chans))
+		}
+	}
+	return samples, sampleRate, nil
+}
+
+// decodeWAVChannels decodes the pcm (8, 16, 24, or 32-bit integer, or 32-bit
+// float) wav file into the samples (-1 to 1) of each channel
+func decodeWAVChannels(content []byte) (chans [][]float64, sampleRate int, err error) {
+	le := binary.LittleEndian
+	if len(content) < 12 || string(content[:4]) != "RIFF" || string(content[8:12]) != "WAVE" {
+		return nil, 0, errors.New("not a wav file")
+	}
+	var format, channels, bitsPerSample int
+	fmtFound := false
+	for pos := 12; pos+8 <= len(content); {
+		id, size := string(content[pos:pos+4]), int(le.Uint32(content[pos+4:pos+8]))
+		pos += 8
+		if size > len(content)-pos {
+			size = len(content) - pos // truncated, or still being recorded
+		}
+		chunk := content[pos : pos+size]
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, 0, errors.New("bad wav fmt chunk")
+			}
+			format = int(le.Uint16(chunk[0:]))
+			channels = int(le.Uint16(chunk[2:]))
+			sampleRate = int(le.Uint32(chunk[4:]))
+			bitsPerSample = int(le.Uint16(chunk[14:]))
+			if format == 0xfffe && size >= 26 { // extensible, the sub format follows
+				format = int(le.Uint16(chunk[24:]))
+			}
+			fmtFound = true
+		case "data":
+			if !fmtFound {
+				return nil, 0, errors.New("wav data chunk precedes the fmt chunk")
+			}
+			chans, err = decodePCM(chunk, format, channels, bitsPerSample)
+			return chans, sampleRate, err
+		}
+		pos += size + size%2 // chunks are padded to an even size
+	}
+	return nil, 0, errors.New("wav file has no data chunk")
+}
+
+func decodePCM(data []byte, format, channels, bitsPerSample int) (chans [][]float64, err error) {
+	le := binary.LittleEndian
+	bytesPerSample := bitsPerSample / 8
+	if channels < 1 || bytesPerSample < 1 {
+		return nil, errors.New("bad wav fmt chunk")
+	}
+	var sample func(b []byte) float64
+	switch {
+	case format == 1 && bitsPerSample == 8:
+		sample = func(b []byte) float64 { return (float64(b[0]) - 128) / 128 }
+	case format == 1 && bitsPerSample == 16:
+		sample = func(b []byte) float64 { return float64(int16(le.Uint16(b))) / (1 << 15) }
+	case format == 1 && bitsPerSample == 24:
+		sample = func(b []byte) float64 {
+			return float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+		}
+	case format == 1 && bitsPerSample == 32:
+		sample = func(b []byte) float64 { return float64(int32(le.Uint32(b))) / (1 << 31) }
+	case format == 3 && bitsPerSample == 32:
+		sample = func(b []byte) float64 { return float64(math.Float32frombits(le.Uint32(b))) }
+	default:
+		return nil, fmt.Errorf("unsupported wav encoding (format %v, %v bits)", format, bitsPerSample)
+	}
+
+	frameLen := bytesPerSample * channels
+	frames := len(data) / frameLen
+	chans = make([][]float64, channels)
+	for c := range chans {
+		chans[c] = make([]float64, frames)
+		for i := range chans[c] {
+			chans[c][i] = sample(data[i*frameLen+c*bytesPerSample:])
+		}
+	}
+	return chans, nil
+}
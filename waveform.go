@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	waveformRes   = 4    // envelope points per character of the sine
+	waveformAlpha = 0.12 // faint so the sine and text remain legible
+)
+
+// addWaveforms sets the amplitude envelope of the linked (wav) audio onto
+// each sine of the songsheet, aligned in time by the playback times
+func addWaveforms(ss *songsheet, origLines []string) error {
+	has, audioPath, err := hasSongsheetAudio(origLines, ss.take)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errors.New("the songsheet has no linked audio for the waveform")
+	}
+	if _, err := readAudioInfo(audioPath); err != nil {
+		return err
+	}
+	content, err := ioutil.ReadFile(audioPath)
+	if err != nil {
+		return err
+	}
+	samples, sampleRate, err := decodeWAV(content)
+	if err != nil {
+		return fmt.Errorf("the waveform requires wav audio: %v", err)
+	}
+
+	// the sines being printed, in order
+	lasses, elemIndexes := elemLasses(ss.elems)
+	charPoss := lasses.charPositions()
+	tl, anchored := newCharTimeline(lasses.charWeights(), charPoss, headerBPM(ss.hc))
+	if !anchored {
+		return errors.New("the waveform requires playback times to align the audio with")
+	}
+
+	// the peak amplitude within the duration
+	peak := func(start, end time.Duration) (max float64) {
+		startI := int(start.Seconds() * float64(sampleRate))
+		endI := int(end.Seconds() * float64(sampleRate))
+		if startI < 0 {
+			startI = 0
+		}
+		if endI > len(samples) {
+			endI = len(samples)
+		}
+		for i := startI; i < endI; i++ {
+			max = math.Max(max, math.Abs(samples[i]))
+		}
+		return max
+	}
+
+	envelopes := make([][]float64, len(lasses))
+	songPeak := 0.0
+	for ci, cp := range charPoss {
+		start, step := tl[ci], (tl[ci+1]-tl[ci])/waveformRes
+		for k := 0; k < waveformRes; k++ {
+			p := peak(start+time.Duration(k)*step, start+time.Duration(k+1)*step)
+			envelopes[cp.lassesIndex] = append(envelopes[cp.lassesIndex], p)
+			songPeak = math.Max(songPeak, p)
+		}
+	}
+	if songPeak == 0 {
+		return errors.New("the audio is silent within the playback times")
+	}
+
+	for i, env := range envelopes {
+		for j := range env {
+			env[j] /= songPeak
+		}
+		sas := lasses[i].sas
+		sas.waveform = env
+		ss.elems[elemIndexes[i]] = sas
+	}
+	return nil
+}
+
+// printWaveform fills the envelope of the audio symmetrically about the
+// sine axis, the envelope is at most the amplitude of the sine
+func (s sine) printWaveform(pdf Pdf, xStart, yStart, humpWidth, maxWidth, amplitude float64) {
+	if len(s.waveform) < 2 {
+		return
+	}
+	x := func(i int) float64 {
+		return xStart + math.Min(float64(i)/(waveformRes*charsToaHump)*humpWidth, maxWidth)
+	}
+	pts := []gofpdf.PointType{}
+	for i, env := range s.waveform {
+		pts = append(pts, gofpdf.PointType{X: x(i), Y: yStart - env*amplitude})
+	}
+	for i := len(s.waveform) - 1; i >= 0; i-- {
+		pts = append(pts, gofpdf.PointType{X: x(i), Y: yStart + s.waveform[i]*amplitude})
+	}
+	pdf.SetAlpha(waveformAlpha, "")
+	pdf.Polygon(pts, "F")
+	pdf.SetAlpha(1.0, "")
+}